@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// `-type all`, registered here as its lookup refers to the other types
+func init() {
+	recordTypes = append(recordTypes, &recordType{
+		name:        "all",
+		description: "All of the types above at once, with their answers aggregated (each record tagged with its type)",
+		lookup: func(r *Resolver, ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+			return r.ResolveAllTypes(ctx, network, hostname)
+		},
+	})
+}
+
+// Look up every other record type for `hostname` concurrently, sharing `ctx`,
+// aggregating the answers into one result: the addresses (and their reverse
+// names) from the address lookup, and the other types' records, each tagged
+// with its type (e.g. "TXT v=spf1 -all"). Each lookup logs its own answers
+// as usual; the hostname only fails when every lookup failed
+func (r *Resolver) ResolveAllTypes(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+	startTime := time.Now()
+	var types []*recordType
+	for _, t := range recordTypes {
+		if t.name != "all" {
+			types = append(types, t)
+		}
+	}
+
+	typeResults := make([]*ResolveResult, len(types))
+	var wg sync.WaitGroup
+	for i, t := range types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if t.lookup == nil {
+				typeResults[i] = r.ResolveHostname(ctx, network, hostname)
+			} else {
+				typeResults[i] = t.lookup(r, ctx, network, hostname)
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := &ResolveResult{Hostname: hostname}
+	failed := 0
+	for i, t := range types {
+		typeResult := typeResults[i]
+		if typeResult.Err != nil {
+			failed++
+			continue
+		}
+		if result.Server == "" {
+			result.Server = typeResult.Server
+		}
+		if t.lookup == nil {
+			result.QueryName = typeResult.QueryName
+			result.IPs = typeResult.IPs
+			result.Reverse = typeResult.Reverse
+			result.ReverseErrs = typeResult.ReverseErrs
+		}
+		for _, record := range typeResult.Records {
+			result.Records = append(result.Records, strings.ToUpper(t.name)+" "+record)
+		}
+	}
+	if failed == len(types) {
+		// the address lookup's error stands for the others
+		result.Err = typeResults[0].Err
+		result.Server = typeResults[0].Server
+	}
+	result.Duration = time.Since(startTime)
+	r.logInfo("All record types for %s: %d of %d looked up, %d address(es) and %d other record(s) in %s\n",
+		r.displayName(hostname), len(types)-failed, len(types), len(result.IPs), len(result.Records), formatDuration(result.Duration))
+	return result
+}
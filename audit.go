@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// record types queried when auditing a name for a CNAME coexisting with other data
+var auditTypes = []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeMX, dns.TypeTXT, dns.TypeNS, dns.TypeSOA}
+
+// Flag a CNAME that coexists with other records at the same name (RFC 1034
+// 3.6.2), most commonly a CNAME at a zone apex alongside its SOA and NS records.
+// The raw responses are inspected, as `net.Resolver` hides the record types
+func (r *Resolver) auditCNAME(ctx context.Context, hostname string) {
+	owner := strings.ToLower(dns.Fqdn(hostname))
+	hasCNAME := false
+	others := map[string]bool{}
+
+	for _, qtype := range auditTypes {
+		msg := new(dns.Msg)
+		msg.SetQuestion(owner, qtype)
+		var resp *dns.Msg
+		_, err := r.withFailover(ctx, func(ns nameServer) error {
+			var err error
+			resp, err = r.exchange(ctx, ns, msg)
+			return err
+		})
+		if err != nil {
+			continue
+		}
+		for _, rr := range append(resp.Answer, resp.Ns...) {
+			if strings.ToLower(rr.Header().Name) != owner {
+				continue
+			}
+			if rr.Header().Rrtype == dns.TypeCNAME {
+				hasCNAME = true
+			} else {
+				others[dns.TypeToString[rr.Header().Rrtype]] = true
+			}
+		}
+	}
+
+	if !hasCNAME || len(others) == 0 {
+		return
+	}
+	types := make([]string, 0, len(others))
+	for t := range others {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	if others["SOA"] {
+		r.logWarning("CNAME at the zone apex for %s: coexists with %s records\n", hostname, strings.Join(types, ", "))
+	} else {
+		r.logWarning("CNAME for %s coexists with other records: %s\n", hostname, strings.Join(types, ", "))
+	}
+}
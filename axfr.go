@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// miekg/dns only reports a failed transfer's rcode via the error message
+var axfrRefused = fmt.Sprintf("dns: bad xfr rcode: %d", dns.RcodeRefused)
+
+// Transfer (AXFR) `zone` from the configured (authoritative) server over TCP,
+// logging every record rather than returning them
+func (r *Resolver) TransferZone(ctx context.Context, zone string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: zone}
+
+	ns := r.servers[0]
+	result.Server = ns.String()
+	addr, err := ns.rawAddr()
+	if err == nil {
+		var records int
+		records, err = r.transferZone(ctx, addr, zone)
+		if err == nil {
+			r.logInfo("Transferred %d records for zone %s from %s\n", records, zone, ns)
+		}
+	}
+	result.Duration = time.Since(startTime)
+
+	if err != nil {
+		if err.Error() == axfrRefused {
+			r.logError("Zone transfer for %s refused by %s; transfers are usually restricted to authorized secondaries\n", zone, ns)
+		} else {
+			r.logError("Zone transfer for %s from %s failed: Error - '%s'\n", zone, ns, err.Error())
+		}
+		result.Err = newResolveError(zone, err)
+		return result
+	}
+	r.logInfo("Duration for transferring %s: %s\n", zone, formatDuration(result.Duration))
+	return result
+}
+
+func (r *Resolver) transferZone(ctx context.Context, addr string, zone string) (int, error) {
+	conn, err := r.dialer("tcp").DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	// the transfer isn't context-aware, so bound it by the context's deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetAxfr(dns.Fqdn(zone))
+	t := &dns.Transfer{Conn: &dns.Conn{Conn: conn}}
+	envelopes, err := t.In(msg, addr)
+	if err != nil {
+		return 0, err
+	}
+
+	records := 0
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			return records, envelope.Error
+		}
+		for _, rr := range envelope.RR {
+			r.logInfo("%s\n", rr.String())
+			records++
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,19 @@
+//go:build linux
+
+package main
+
+import "syscall"
+
+// a dialer Control func binding the socket to the network interface `iface`
+// (SO_BINDTODEVICE), which may require CAP_NET_RAW
+func bindDeviceControl(iface string) (func(network, address string, c syscall.RawConn) error, error) {
+	return func(network, address string, c syscall.RawConn) error {
+		var bindErr error
+		if err := c.Control(func(fd uintptr) {
+			bindErr = syscall.BindToDevice(int(fd), iface)
+		}); err != nil {
+			return err
+		}
+		return bindErr
+	}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// binding to a device (SO_BINDTODEVICE) is Linux-only
+func bindDeviceControl(iface string) (func(network, address string, c syscall.RawConn) error, error) {
+	return nil, errors.New("binding to a network interface is only supported on Linux")
+}
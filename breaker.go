@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Cancels a run once `max` of its hostnames have failed, so a run against a
+// server that's down fails fast rather than working through every hostname;
+// results arrive from concurrent goroutines, so the count is serialized
+type failureBreaker struct {
+	max    int
+	failed func(*ResolveResult) bool
+	mu     sync.Mutex
+	count  int
+	cancel context.CancelCauseFunc
+}
+
+func newFailureBreaker(max int, failed func(*ResolveResult) bool) *failureBreaker {
+	return &failureBreaker{max: max, failed: failed}
+}
+
+// derive the context for a run (or -interval cycle), resetting the count
+func (b *failureBreaker) start(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancelCause(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.count = 0
+	b.cancel = cancel
+	return ctx
+}
+
+// count `result` if it failed, canceling the run at the threshold
+func (b *failureBreaker) record(result *ResolveResult) {
+	if !b.failed(result) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.count++
+	if b.count == b.max {
+		b.cancel(fmt.Errorf("%w: %d hostnames failed", ErrMaxFailures, b.max))
+	}
+}
+
+// whether the current run was aborted at the threshold
+func (b *failureBreaker) tripped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count >= b.max
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Query `hostname` twice in quick succession via the same server, reporting
+// the cold and warm latencies: a much faster second query indicates that the
+// (recursive) server answered it from its cache
+func (r *Resolver) ResolveCacheProbe(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	var ips []net.IP
+	var cold, warm time.Duration
+	ns, err := r.withFailover(ctx, func(ns nameServer) error {
+		var err error
+		queryStart := time.Now()
+		if _, err = ns.resolver.LookupIP(ctx, string(network), hostname); err != nil {
+			return err
+		}
+		cold = time.Since(queryStart)
+
+		queryStart = time.Now()
+		ips, err = ns.resolver.LookupIP(ctx, string(network), hostname)
+		warm = time.Since(queryStart)
+		return err
+	})
+	result.Server = ns.String()
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		r.logError("Failed to resolve: %s Error - '%s'\n", hostname, shortError(err))
+		result.Err = newResolveError(hostname, err)
+		return result
+	}
+
+	ratio := 0.0
+	if cold > 0 {
+		ratio = float64(warm) / float64(cold)
+	}
+	r.logInfo("Cache probe for %s via %s: cold %.2f ms, warm %.2f ms (warm/cold %.2f)\n",
+		r.displayName(hostname), ns, float64(cold.Microseconds())/1000, float64(warm.Microseconds())/1000, ratio)
+
+	if !r.rawIPv6 {
+		ips = unmapIPv4(ips)
+	}
+	result.IPs = ips
+	return result
+}
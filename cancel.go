@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// Why the run (or -interval cycle) with context `ctx` was cut short, for its
+// summary: the timeout or deadline, an interrupt, or -max-failures; empty when
+// it completed normally. Must be called before the run's context is canceled
+func cancelReason(ctx context.Context) string {
+	cause := context.Cause(ctx)
+	switch {
+	case cause == nil:
+		return ""
+	case errors.Is(cause, ErrMaxFailures):
+		return "Aborted at -max-failures"
+	case errors.Is(cause, context.DeadlineExceeded):
+		return "Deadline exceeded"
+	case cause == context.Canceled:
+		return "Interrupted"
+	}
+	// e.g. the signal received, for an interrupt
+	return "Interrupted (" + cause.Error() + ")"
+}
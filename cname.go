@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Query the CNAME chain for a resolved hostname via the lower-level client
+// (`net.Resolver` only returns the canonical name, not the names in between)
+func (r *Resolver) checkCNAMEs(ctx context.Context, result *ResolveResult) {
+	if !r.queryCNAME || result.Err != nil {
+		return
+	}
+	name := result.Hostname
+	if result.QueryName != "" {
+		name = result.QueryName
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), dns.TypeA)
+	var resp *dns.Msg
+	_, err := r.withFailover(ctx, func(ns nameServer) error {
+		var err error
+		resp, err = r.exchange(ctx, ns, msg)
+		return err
+	})
+	if err != nil {
+		r.logWarning("Failed to look up the CNAME chain for %s: '%s'\n", name, err.Error())
+		return
+	}
+	result.CNAMEs = cnameChain(name, resp.Answer)
+}
+
+// how many CNAME hops `lookupChain` follows, as resolvers commonly limit them
+const maxCNAMEHops = 8
+
+// Query `name` `qtype` via the lower-level client, returning the answer
+// records along its CNAME chain. A chain crossing zones may be answered a hop
+// at a time (e.g. by a server that isn't authoritative for the target's
+// zone): when an answer stops at a CNAME, its target is queried in turn
+func (r *Resolver) lookupChain(ctx context.Context, name string, qtype uint16) ([]dns.RR, error) {
+	var answers []dns.RR
+	current := dns.Fqdn(name)
+	seen := map[string]bool{}
+	for hop := 0; hop < maxCNAMEHops && !seen[strings.ToLower(current)]; hop++ {
+		seen[strings.ToLower(current)] = true
+		msg := new(dns.Msg)
+		msg.SetQuestion(current, qtype)
+		var resp *dns.Msg
+		_, err := r.withFailover(ctx, func(ns nameServer) error {
+			var err error
+			resp, err = r.exchange(ctx, ns, msg)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, resp.Answer...)
+
+		chain := cnameChain(current, resp.Answer)
+		if len(chain) == 0 {
+			break
+		}
+		target := chain[len(chain)-1]
+		if hasRecordsFor(target, qtype, resp.Answer) {
+			break
+		}
+		current = target
+	}
+	return answers, nil
+}
+
+// whether `rrs` has a record of `qtype` for `name`
+func hasRecordsFor(name string, qtype uint16, rrs []dns.RR) bool {
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == qtype && strings.EqualFold(rr.Header().Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// the targets of the CNAME records in `rrs` chained from `name`, in order;
+// a chain looping back on itself stops once every record has been followed
+func cnameChain(name string, rrs []dns.RR) []string {
+	targets := map[string]string{}
+	for _, rr := range rrs {
+		if cname, ok := rr.(*dns.CNAME); ok {
+			targets[strings.ToLower(cname.Hdr.Name)] = cname.Target
+		}
+	}
+
+	var chain []string
+	current := strings.ToLower(dns.Fqdn(name))
+	for len(chain) < len(targets) {
+		target, ok := targets[current]
+		if !ok {
+			break
+		}
+		chain = append(chain, target)
+		current = strings.ToLower(target)
+	}
+	return chain
+}
@@ -0,0 +1,44 @@
+package main
+
+import "sync"
+
+// Collects results pushed concurrently by the goroutines resolving them,
+// returning them either in completion order or in the original input order
+type ResultCollector struct {
+	mu        sync.Mutex
+	byIndex   []*ResolveResult // by input position; nil until completed
+	completed []*ResolveResult // in the order they were added
+}
+
+// a collector for `n` results, indexed by their input position
+func NewResultCollector(n int) *ResultCollector {
+	return &ResultCollector{byIndex: make([]*ResolveResult, n)}
+}
+
+// Add the result for the input at `index`; safe for concurrent use
+func (c *ResultCollector) Add(index int, result *ResolveResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byIndex[index] = result
+	c.completed = append(c.completed, result)
+}
+
+// the results added so far, in input order; inputs not yet completed are skipped
+func (c *ResultCollector) InOrder() []*ResolveResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := make([]*ResolveResult, 0, len(c.completed))
+	for _, result := range c.byIndex {
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// the results added so far, in the order they completed
+func (c *ResultCollector) InCompletionOrder() []*ResolveResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*ResolveResult(nil), c.completed...)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestResultCollectorOrdering(t *testing.T) {
+	const n = 50
+	// complete the inputs in a shuffled order, one at a time
+	completion := rand.New(rand.NewSource(1)).Perm(n)
+	turns := make([]chan struct{}, n+1)
+	for i := range turns {
+		turns[i] = make(chan struct{})
+	}
+
+	c := NewResultCollector(n)
+	var wg sync.WaitGroup
+	for turn, index := range completion {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-turns[turn]
+			c.Add(index, &ResolveResult{Hostname: fmt.Sprintf("host%d", index)})
+			close(turns[turn+1])
+		}()
+	}
+	close(turns[0])
+	wg.Wait()
+
+	inOrder := c.InOrder()
+	if len(inOrder) != n {
+		t.Fatalf("got %d results in input order, want %d", len(inOrder), n)
+	}
+	for i, result := range inOrder {
+		if want := fmt.Sprintf("host%d", i); result.Hostname != want {
+			t.Errorf("input order: got %s at %d, want %s", result.Hostname, i, want)
+		}
+	}
+	inCompletionOrder := c.InCompletionOrder()
+	if len(inCompletionOrder) != n {
+		t.Fatalf("got %d results in completion order, want %d", len(inCompletionOrder), n)
+	}
+	for turn, result := range inCompletionOrder {
+		if want := fmt.Sprintf("host%d", completion[turn]); result.Hostname != want {
+			t.Errorf("completion order: got %s at %d, want %s", result.Hostname, turn, want)
+		}
+	}
+}
+
+// adding and reading at once, for `go test -race`
+func TestResultCollectorConcurrent(t *testing.T) {
+	const n = 200
+	c := NewResultCollector(n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.Add(i, &ResolveResult{Hostname: fmt.Sprintf("host%d", i)})
+		}()
+		go func() {
+			defer wg.Done()
+			// a partial view is still ordered
+			last := -1
+			for _, result := range c.InOrder() {
+				var index int
+				fmt.Sscanf(result.Hostname, "host%d", &index)
+				if index <= last {
+					t.Errorf("got host%d after host%d", index, last)
+				}
+				last = index
+			}
+			c.InCompletionOrder()
+		}()
+	}
+	wg.Wait()
+	if got := len(c.InOrder()); got != n {
+		t.Errorf("got %d results, want %d", got, n)
+	}
+	if got := len(c.InCompletionOrder()); got != n {
+		t.Errorf("got %d results in completion order, want %d", got, n)
+	}
+}
+
+func TestResolveHostnamesOrder(t *testing.T) {
+	ts := newTestServer(t)
+	r := newTestResolver(t, ts, nil)
+	hostnames := []string{"v4.test", "nxdomain.test", "ok.test", "multi.test", "servfail.test", "alias.test"}
+	results := r.ResolveHostnames(testContext(t), IPv4, hostnames)
+	if len(results) != len(hostnames) {
+		t.Fatalf("got %d results, want %d", len(results), len(hostnames))
+	}
+	for i, result := range results {
+		if result.Hostname != hostnames[i] {
+			t.Errorf("got %s at %d, want %s", result.Hostname, i, hostnames[i])
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// the columns selectable via `-columns`, for the compact and CSV output
+var outputColumns = []string{"hostname", "ip", "reverse", "duration", "ttl", "server", "error"}
+
+// the columns used when `-columns` isn't given; `ttl` costs an extra query per hostname
+const defaultCSVColumns = "hostname,ip,reverse,duration,error"
+
+// split and validate a comma-separated list of columns
+func parseColumns(s string) ([]string, error) {
+	var columns []string
+	for _, column := range strings.Split(s, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		valid := false
+		for _, name := range outputColumns {
+			valid = valid || column == name
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown column '%s' (expected %s)", column, strings.Join(outputColumns, ","))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func hasColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// the value of `column` for `result` as a plain string, empty when it doesn't apply
+func (r *Resolver) columnValue(result *ResolveResult, column string) string {
+	switch column {
+	case "hostname":
+		return r.displayName(result.Hostname)
+	case "ip":
+		strs := make([]string, len(result.IPs))
+		for i, ip := range result.IPs {
+			strs[i] = ipString(ip)
+		}
+		return strings.Join(strs, " ")
+	case "reverse":
+		return strings.Join(reverseNames(result), " ")
+	case "duration":
+		return strconv.FormatInt(result.Duration.Milliseconds(), 10)
+	case "ttl":
+		if !result.HasTTL {
+			return ""
+		}
+		return strconv.FormatInt(int64(result.TTL.Seconds()), 10)
+	case "server":
+		return result.Server
+	case "error":
+		if result.Err == nil {
+			return ""
+		}
+		return shortError(result.Err)
+	}
+	return ""
+}
+
+// single-line rendering of just the selected `columns`, in order, in the
+// style of `compactLine`; columns that don't apply are left out
+func (r *Resolver) compactColumns(result *ResolveResult, columns []string) string {
+	var fields []string
+	for _, column := range columns {
+		value := r.columnValue(result, column)
+		if value == "" {
+			continue
+		}
+		switch column {
+		case "ip":
+			value = fmt.Sprintf("[%s]", r.limitAddrs(result.IPs))
+		case "reverse":
+			value = fmt.Sprintf("(%s)", r.limitNames(reverseNames(result)))
+		case "duration":
+			value = formatCompactDuration(result.Duration)
+		case "ttl":
+			value = fmt.Sprintf("ttl=%ss", value)
+		case "server":
+			value = "via " + value
+		case "error":
+			value = fmt.Sprintf("FAILED (%s)", value)
+		}
+		fields = append(fields, value)
+	}
+	return strings.Join(fields, " ")
+}
+
+// Writes a header and then a CSV row per result as soon as it completes;
+// results arrive from concurrent goroutines, so writes are serialized
+type csvWriter struct {
+	mu      sync.Mutex
+	w       *csv.Writer
+	r       *Resolver
+	columns []string
+}
+
+func newCSVWriter(w io.Writer, r *Resolver, columns []string) *csvWriter {
+	cw := &csvWriter{w: csv.NewWriter(w), r: r, columns: columns}
+	cw.w.Write(columns)
+	cw.w.Flush()
+	return cw
+}
+
+func (cw *csvWriter) WriteResult(result *ResolveResult) {
+	row := make([]string, len(cw.columns))
+	for i, column := range cw.columns {
+		row[i] = cw.r.columnValue(result, column)
+	}
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.w.Write(row)
+	cw.w.Flush()
+	if err := cw.w.Error(); err != nil {
+		LogError("Failed to write result for %s: %s\n", result.Hostname, err.Error())
+	}
+}
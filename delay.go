@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Resolve the hostnames one at a time, in order, waiting `d` between
+// consecutive hostnames, for the gentlest load on a fragile server; this
+// overrides `SetConcurrency`, and batches resolved at once take turns
+func (r *Resolver) SetDelay(d time.Duration) {
+	if d > 0 {
+		r.delay = d
+		r.limit = make(chan struct{}, 1)
+	}
+}
+
+// resolve the `hostnames` in order, waiting the delay before each but the
+// first; once the context is done, the remaining hostnames fail without waiting.
+// The dependencies of each hostname must come before it (see `dependencies.order`)
+func (r *Resolver) resolveSerially(ctx context.Context, network NetworkString, hostnames []string) []*ResolveResult {
+	results := make([]*ResolveResult, len(hostnames))
+	waits := newDependencyWaits(r.dependencies, hostnames)
+	for i, hostname := range hostnames {
+		if results[i] = waits.await(r, hostname); results[i] != nil {
+			r.runHooks(results[i])
+			waits.finish(i, results[i])
+			continue
+		}
+		r.limit <- struct{}{}
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(r.delay):
+			}
+		}
+		results[i] = r.resolveChecked(ctx, network, hostname)
+		<-r.limit
+		waits.finish(i, results[i])
+	}
+	return results
+}
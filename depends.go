@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// the hostnames each hostname depends on, keyed by (lowercased, unqualified)
+// hostname, from `-depends-file`: a hostname is only resolved once its
+// dependencies have, and is skipped when one of them failed
+type dependencies struct {
+	hostnames []string // every hostname listed, in file order
+	deps      map[string][]string
+}
+
+// read `hostname depends-on other-hostname` lines; a hostname may be repeated
+// to depend on several others. Blank lines and `#` comments are skipped, and
+// a dependency cycle is an error
+func loadDependencies(path string) (*dependencies, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := &dependencies{deps: map[string][]string{}}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 || fields[1] != "depends-on" {
+			return nil, fmt.Errorf("line %d: expected 'hostname depends-on other-hostname'", lineNo)
+		}
+
+		for _, hostname := range []string{fields[0], fields[2]} {
+			if key := expectKey(hostname); !seen[key] {
+				seen[key] = true
+				d.hostnames = append(d.hostnames, hostname)
+			}
+		}
+		key := expectKey(fields[0])
+		d.deps[key] = append(d.deps[key], expectKey(fields[2]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return d, d.checkCycles()
+}
+
+// fails with the first dependency cycle found, e.g. "a -> b -> a"
+func (d *dependencies) checkCycles() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(key string) error
+	visit = func(key string) error {
+		switch state[key] {
+		case visiting:
+			start := 0
+			for path[start] != key {
+				start++
+			}
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path[start:], " -> "), key)
+		case visited:
+			return nil
+		}
+		state[key] = visiting
+		path = append(path, key)
+		for _, dep := range d.deps[key] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[key] = visited
+		return nil
+	}
+	for _, hostname := range d.hostnames {
+		if err := visit(expectKey(hostname)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The `hostnames` with each one's dependencies moved ahead of it, otherwise
+// in order, so they're resolved first (e.g. one at a time, with -delay)
+func (d *dependencies) order(hostnames []string) []string {
+	byKey := map[string][]string{}
+	for _, hostname := range hostnames {
+		key := expectKey(hostname)
+		byKey[key] = append(byKey[key], hostname)
+	}
+	ordered := make([]string, 0, len(hostnames))
+	placed := map[string]bool{}
+	var place func(key string)
+	place = func(key string) {
+		if placed[key] {
+			return
+		}
+		placed[key] = true
+		for _, dep := range d.deps[key] {
+			place(dep)
+		}
+		ordered = append(ordered, byKey[key]...)
+	}
+	for _, hostname := range hostnames {
+		place(expectKey(hostname))
+	}
+	return ordered
+}
+
+// Tracks the results of the hostnames resolved together, so each one's
+// dependents can wait for them; nil when there are no dependencies
+type dependencyWaits struct {
+	deps    *dependencies
+	index   map[string]int // the first position of each hostname
+	done    []chan struct{}
+	results []*ResolveResult // each set before its `done` is closed
+}
+
+func newDependencyWaits(deps *dependencies, hostnames []string) *dependencyWaits {
+	if deps == nil {
+		return nil
+	}
+	w := &dependencyWaits{
+		deps:    deps,
+		index:   map[string]int{},
+		done:    make([]chan struct{}, len(hostnames)),
+		results: make([]*ResolveResult, len(hostnames)),
+	}
+	for i, hostname := range hostnames {
+		if _, ok := w.index[expectKey(hostname)]; !ok {
+			w.index[expectKey(hostname)] = i
+		}
+		w.done[i] = make(chan struct{})
+	}
+	return w
+}
+
+// Wait for the dependencies of `hostname` among the hostnames resolved
+// together, returning the result for skipping it when one of them failed,
+// or nil to go ahead and resolve it
+func (w *dependencyWaits) await(r *Resolver, hostname string) *ResolveResult {
+	if w == nil {
+		return nil
+	}
+	for _, dep := range w.deps.deps[expectKey(hostname)] {
+		i, ok := w.index[dep]
+		if !ok {
+			continue
+		}
+		<-w.done[i]
+		if w.results[i].Err != nil {
+			depName := w.results[i].Hostname
+			r.logWarning("Skipping %s, as its dependency %s failed to resolve\n", r.displayName(hostname), r.displayName(depName))
+			return &ResolveResult{Hostname: hostname, Err: newResolveError(hostname, fmt.Errorf("%w: %s", ErrDependencyFailed, depName))}
+		}
+	}
+	return nil
+}
+
+// record the result of the `i`th hostname, releasing its dependents
+func (w *dependencyWaits) finish(i int, result *ResolveResult) {
+	if w == nil {
+		return
+	}
+	w.results[i] = result
+	close(w.done[i])
+}
@@ -0,0 +1,27 @@
+package main
+
+// The `-dnsserver` queries only go to the server given because its
+// `net.Resolver` dials it; with `PreferGo: false` (or no Dial func) the
+// system's resolver may be used instead, silently querying its own servers.
+// Warn at startup about configurations that would bypass the dial func
+func (r *Resolver) CheckDialers() {
+	for _, ns := range r.servers {
+		if ns.resolver.PreferGo && ns.resolver.Dial != nil {
+			continue
+		}
+		switch {
+		case ns.addr != "":
+			r.logWarning("Queries for %s may bypass its dial func and go to the system's DNS servers instead (the resolver needs PreferGo and a Dial func)\n", ns)
+		case r.dialControl != nil:
+			r.logWarning("The socket options (e.g. -bind-device, -udp-rcvbuf) may not apply via the system's resolver, which can bypass the dial func; use -dnsserver to ensure they do\n")
+		}
+	}
+}
+
+// a lookup via a configured server succeeded without dialing it, so the
+// answer may have come from the system's resolver rather than `ns`
+func (r *Resolver) warnDialerBypassed(ns nameServer) {
+	r.dialBypassed.Do(func() {
+		r.logWarning("A lookup via %s didn't go through its dial func, so may have been answered by the system's resolver instead\n", ns)
+	})
+}
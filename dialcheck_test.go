@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// every query reaching the fake server was sent over a socket from the
+// resolver's dial func, for both the Go resolver and the lower-level client
+func TestQueriesGoThroughDialer(t *testing.T) {
+	ts := newTestServer(t)
+	for _, raw := range []bool{false, true} {
+		var dials atomic.Int64
+		r := newTestResolver(t, ts, &dials)
+		if raw {
+			r.use0x20 = true
+		}
+		before := ts.queries.Load()
+		for _, hostname := range []string{"ok.test", "multi.test", "nxdomain.test"} {
+			r.ResolveHostname(testContext(t), "ip", hostname)
+		}
+		queries := ts.queries.Load() - before
+		if queries == 0 {
+			t.Fatalf("raw %t: no queries reached the server", raw)
+		}
+		// one UDP socket per query
+		if got := dials.Load(); got != queries {
+			t.Errorf("raw %t: %d dials for %d queries", raw, got, queries)
+		}
+	}
+}
+
+func TestCheckDialers(t *testing.T) {
+	ts := newTestServer(t)
+	stdout, stderr := captureLogs(t)
+	r := newTestResolver(t, ts, nil)
+	r.noLog = false
+	r.CheckDialers()
+	if logged := stdout.String() + stderr.String(); logged != "" {
+		t.Errorf("got %q for the configured server's resolver", logged)
+	}
+
+	// a resolver that may hand the queries to the system's
+	r.servers[0].resolver = &net.Resolver{PreferGo: false}
+	r.CheckDialers()
+	if logged := stderr.String(); !strings.Contains(logged, "may bypass its dial func") {
+		t.Errorf("got %q, want a warning about the bypassed dial func", logged)
+	}
+}
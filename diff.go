@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// normalized (IPv4-mapped addresses unmapped), sorted and deduplicated address strings
+func sortedIPStrings(ips []net.IP) []string {
+	seen := map[string]bool{}
+	var strs []string
+	for _, ip := range unmapIPv4(ips) {
+		if s := ip.String(); !seen[s] {
+			seen[s] = true
+			strs = append(strs, s)
+		}
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+// the addresses only in `a`, and those only in `b`
+func diffIPSets(a, b []string) (onlyA, onlyB []string) {
+	inA := map[string]bool{}
+	inB := map[string]bool{}
+	for _, s := range a {
+		inA[s] = true
+	}
+	for _, s := range b {
+		inB[s] = true
+		if !inA[s] {
+			onlyB = append(onlyB, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s] {
+			onlyA = append(onlyA, s)
+		}
+	}
+	return onlyA, onlyB
+}
+
+// Resolve `hostname` via the configured server(s) and via the system's default
+// resolver, logging whether the (normalized, sorted) address sets differ
+func (r *Resolver) ResolveDiffDefault(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	ips, ns, err := r.lookupIP(ctx, network, hostname)
+	result.Server = ns.String()
+	defaultIPs, defaultErr := net.DefaultResolver.LookupIP(ctx, string(network), hostname)
+	result.Duration = time.Since(startTime)
+
+	switch {
+	case err != nil && defaultErr != nil:
+		r.logError("Failed to resolve %s via both %s and the default resolver: Error - '%s' / '%s'\n", hostname, ns, shortError(err), shortError(defaultErr))
+		result.Err = newResolveError(hostname, err)
+		return result
+	case err != nil:
+		r.logWarning("Differs for %s: failed via %s ('%s'), default resolver: %s\n", hostname, ns, shortError(err), strings.Join(sortedIPStrings(defaultIPs), ", "))
+		result.Err = newResolveError(hostname, err)
+		return result
+	case defaultErr != nil:
+		r.logWarning("Differs for %s: %s via %s, failed via the default resolver ('%s')\n", hostname, strings.Join(sortedIPStrings(ips), ", "), ns, shortError(defaultErr))
+	default:
+		onlyCustom, onlyDefault := diffIPSets(sortedIPStrings(ips), sortedIPStrings(defaultIPs))
+		if len(onlyCustom) == 0 && len(onlyDefault) == 0 {
+			r.logInfo("Matches for %s via %s and the default resolver: %s\n", hostname, ns, strings.Join(sortedIPStrings(ips), ", "))
+		} else {
+			r.logWarning("Differs for %s: only via %s: [%s], only via the default resolver: [%s]\n", hostname, ns, strings.Join(onlyCustom, ", "), strings.Join(onlyDefault, ", "))
+		}
+	}
+
+	if !r.rawIPv6 {
+		ips = unmapIPv4(ips)
+	}
+	result.IPs = ips
+	return result
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/rand"
+
+	"github.com/miekg/dns"
+)
+
+// Randomize the case of the letters in `msg`'s query name (DNS 0x20), returning
+// a copy: servers echo the question as sent, so a spoofed response would have
+// to guess the case too. The case comes from crypto/rand, as it must be
+// unpredictable (so `-seed` doesn't apply)
+func randomizeCase(msg *dns.Msg) *dns.Msg {
+	msg = msg.Copy()
+	name := []byte(msg.Question[0].Name)
+	bits := make([]byte, len(name))
+	rand.Read(bits)
+	for i, c := range name {
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+			name[i] = c | 0x20
+			if bits[i]&1 == 1 {
+				name[i] &^= 0x20
+			}
+		}
+	}
+	msg.Question[0].Name = string(name)
+	return msg
+}
+
+// Warn when the response to `msg` from `addr` doesn't echo its query name's
+// case exactly (`-0x20`): the response may be spoofed, or the server (or a
+// middlebox) rewrites the names
+func (r *Resolver) check0x20(addr string, msg, resp *dns.Msg) {
+	sent := msg.Question[0].Name
+	if len(resp.Question) == 0 {
+		r.logWarning("0x20: the response to %s from %s has no question section, so its case can't be checked\n", sent, addr)
+		return
+	}
+	if got := resp.Question[0].Name; got != sent {
+		r.logWarning("0x20 mismatch: queried %s, but the response from %s is for %s; it may be spoofed, or the server (or a middlebox) rewrites the case\n", sent, addr, got)
+	}
+}
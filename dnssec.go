@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Look up the DNSKEY or DS (`qtype`) records for the zone `hostname` via the
+// lower-level client, with the DO bit set, logging each record with its key
+// tag, the field matching a DS to the DNSKEY it covers. A zone without these
+// records is reported as unsigned rather than failing
+func (r *Resolver) ResolveDNSSEC(ctx context.Context, hostname string, qtype uint16) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+	typeName := dns.TypeToString[qtype]
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(hostname), qtype)
+	// the DO bit asks for the DNSSEC records (and signatures) to be included
+	msg.SetEdns0(4096, true)
+	var resp *dns.Msg
+	queryCtx, answered := withServerRecorder(ctx)
+	ns, err := r.withFailover(queryCtx, func(ns nameServer) error {
+		var err error
+		resp, err = r.exchange(queryCtx, ns, msg)
+		return err
+	})
+	result.Server = answered.serverOr(ns)
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		r.logError("Failed to look up %s records for %s via %s: Error - '%s'\n", typeName, hostname, result.Server, err.Error())
+		result.Err = newResolveError(hostname, err)
+		return result
+	}
+
+	for _, rr := range resp.Answer {
+		switch rec := rr.(type) {
+		case *dns.DNSKEY:
+			role := "ZSK"
+			if rec.Flags&dns.SEP != 0 {
+				role = "KSK"
+			}
+			result.Records = append(result.Records, fmt.Sprintf("key tag %d: %s flags=%d algorithm=%s key=%s",
+				rec.KeyTag(), role, rec.Flags, dns.AlgorithmToString[rec.Algorithm], rec.PublicKey))
+		case *dns.DS:
+			result.Records = append(result.Records, fmt.Sprintf("key tag %d: algorithm=%s digest type=%s digest=%s",
+				rec.KeyTag, dns.AlgorithmToString[rec.Algorithm], dns.HashToString[rec.DigestType], rec.Digest))
+		}
+	}
+	if len(result.Records) == 0 {
+		r.logInfo("%s records for %s via %s: none (unsigned)\n", typeName, r.displayName(hostname), result.Server)
+		return result
+	}
+
+	r.logInfo("%s records for %s via %s: %d\n", typeName, r.displayName(hostname), result.Server, len(result.Records))
+	for _, record := range result.Records {
+		r.logInfo("  %s\n", record)
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Write the results as a Graphviz graph once the run completes: hostnames
+// (boxes) with edges along their CNAME chains to the addresses (ellipses) of
+// the canonical names, so hostnames sharing an address or a CNAME target are
+// connected; failed hostnames are drawn in red. Render it with e.g.
+// `dot -Tsvg`
+func writeDot(w io.Writer, results []*ResolveResult) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph dns {")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+	fmt.Fprintln(bw, "\tnode [shape=box];")
+
+	// nodes and edges shared between hostnames are written once, in the order first seen
+	written := map[string]bool{}
+	writeOnce := func(line string) {
+		if !written[line] {
+			written[line] = true
+			fmt.Fprintln(bw, line)
+		}
+	}
+
+	for _, result := range results {
+		hostname := plainHostname(result.Hostname)
+		if result.Err != nil {
+			writeOnce(fmt.Sprintf("\t%q [color=red, tooltip=%q];", hostname, shortError(result.Err)))
+			continue
+		}
+		writeOnce(fmt.Sprintf("\t%q;", hostname))
+
+		from := hostname
+		for _, target := range result.CNAMEs {
+			target = strings.TrimSuffix(target, ".")
+			writeOnce(fmt.Sprintf("\t%q [style=dashed];", target))
+			writeOnce(fmt.Sprintf("\t%q -> %q [label=\"CNAME\"];", from, target))
+			from = target
+		}
+		for _, ip := range result.IPs {
+			addr := ipString(ip)
+			writeOnce(fmt.Sprintf("\t%q [shape=ellipse];", addr))
+			writeOnce(fmt.Sprintf("\t%q -> %q;", from, addr))
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// whether durations are displayed human-friendly (`2m3s`) rather than in
+// milliseconds, set via `-duration-format`
+var humanDurations bool
+
+// Display durations as "human" (e.g. `2m3s`, `1.234s`, `12ms`) or "ms" (the
+// default, e.g. `123456 ms`); returns false for an unknown format
+func SetDurationFormat(format string) bool {
+	switch format {
+	case "human":
+		humanDurations = true
+	case "ms":
+		humanDurations = false
+	default:
+		return false
+	}
+	return true
+}
+
+// `d` for the log lines, e.g. `123 ms`
+func formatDuration(d time.Duration) string {
+	if humanDurations {
+		return humanDuration(d)
+	}
+	return fmt.Sprintf("%d ms", d.Milliseconds())
+}
+
+// `d` for the compact lines, e.g. `123ms`
+func formatCompactDuration(d time.Duration) string {
+	if humanDurations {
+		return humanDuration(d)
+	}
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+// `d` rounded to a precision that suits its size: whole seconds from a
+// minute up, milliseconds below
+func humanDuration(d time.Duration) string {
+	if d >= time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	defer SetDurationFormat("ms")
+	tests := []struct {
+		format  string
+		d       time.Duration
+		want    string
+		compact string
+	}{
+		{"ms", 1234567 * time.Microsecond, "1234 ms", "1234ms"},
+		{"ms", 0, "0 ms", "0ms"},
+		{"human", 1234567 * time.Microsecond, "1.235s", "1.235s"},
+		{"human", 12 * time.Millisecond, "12ms", "12ms"},
+		{"human", 123456 * time.Millisecond, "2m3s", "2m3s"},
+	}
+	for _, tt := range tests {
+		if !SetDurationFormat(tt.format) {
+			t.Fatalf("unknown format %s", tt.format)
+		}
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("%s: formatDuration(%s) = %s, want %s", tt.format, tt.d, got, tt.want)
+		}
+		if got := formatCompactDuration(tt.d); got != tt.compact {
+			t.Errorf("%s: formatCompactDuration(%s) = %s, want %s", tt.format, tt.d, got, tt.compact)
+		}
+	}
+	if SetDurationFormat("seconds") {
+		t.Error("accepted an unknown format")
+	}
+}
+
+// the latency check's messages follow -duration-format too
+func TestCheckLatencyDuration(t *testing.T) {
+	defer SetDurationFormat("ms")
+	SetDurationFormat("human")
+	r := &Resolver{maxLatency: 100 * time.Millisecond, noLog: true}
+	result := &ResolveResult{Hostname: "slow.test", Duration: 1500 * time.Millisecond}
+	r.checkLatency(result)
+	if result.LatencyErr == nil || result.LatencyErr.Error() != "maximum latency exceeded: 1.5s (max 100ms)" {
+		t.Errorf("got %v", result.LatencyErr)
+	}
+}
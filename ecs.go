@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// Parse the `-ecs` client subnet (e.g. "203.0.113.0/24"), keeping only its
+// network bits, as only those are sent
+func parseECS(subnet string) (*net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, err
+	}
+	return ipNet, nil
+}
+
+// a copy of `msg` carrying the configured client subnet (RFC 7871), added to
+// its OPT record (created if there's none yet)
+func (r *Resolver) withECS(msg *dns.Msg) *dns.Msg {
+	msg = msg.Copy()
+	opt := msg.IsEdns0()
+	if opt == nil {
+		msg.SetEdns0(dns.DefaultMsgSize, false)
+		opt = msg.IsEdns0()
+	}
+	ones, _ := r.ecs.Mask.Size()
+	subnet := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        1,
+		SourceNetmask: uint8(ones),
+		Address:       r.ecs.IP,
+	}
+	if r.ecs.IP.To4() == nil {
+		subnet.Family = 2
+	}
+	opt.Option = append(opt.Option, subnet)
+	return msg
+}
+
+// Log the scope the server returned for the client subnet of the query for
+// `qname`: the prefix length the answer is valid for, where 0 means it's the
+// same for every client. Servers that don't support ECS leave it out of the response
+func (r *Resolver) logECSScope(addr, qname string, resp *dns.Msg) {
+	if opt := resp.IsEdns0(); opt != nil {
+		for _, option := range opt.Option {
+			if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+				r.logInfo("ECS for %s from %s: %s/%d, scope /%d\n", qname, addr, subnet.Address, subnet.SourceNetmask, subnet.SourceScope)
+				return
+			}
+		}
+	}
+	r.logInfo("No ECS in the response for %s from %s: the server ignores the client subnet, so the answer isn't geo-targeted\n", qname, addr)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	// the DNS server address provided isn't a valid IP address
+	ErrInvalidDNSServer = errors.New("Invalid ip address")
+	// the lookup succeeded but returned no addresses
+	ErrNoAddresses = errors.New("no addresses found")
+	// the hostname breaks the DNS rules for names, so isn't queried
+	ErrInvalidHostname = errors.New("invalid hostname")
+	// the hostname resolved, but slower than the configured maximum latency
+	ErrLatencyExceeded = errors.New("maximum latency exceeded")
+	// an answer record's TTL was below the configured minimum
+	ErrTTLBelowMinimum = errors.New("TTL below minimum")
+	// the run was aborted once the configured number of hostnames failed
+	ErrMaxFailures = errors.New("maximum failures reached")
+	// the hostname wasn't resolved, as a hostname it depends on (`-depends-file`) failed
+	ErrDependencyFailed = errors.New("dependency failed")
+	// none of the configured DNS servers responded to the startup probe
+	ErrServerUnreachable = errors.New("DNS server unreachable")
+	// a query would have been sent via a network interface other than those required (`-require-interface`)
+	ErrInterfaceNotAllowed = errors.New("interface not allowed")
+)
+
+// Wraps a failed forward lookup with the hostname and how it failed,
+// so callers can use `errors.As` rather than matching on strings
+type ResolveError struct {
+	Hostname string
+	NotFound bool // NXDOMAIN (or no records for the network type)
+	Timeout  bool
+	ServFail bool // SERVFAIL, i.e. a problem with the server rather than the name
+	Err      error
+}
+
+func newResolveError(hostname string, err error) *ResolveError {
+	resolveErr := &ResolveError{Hostname: hostname, Err: err, ServFail: isServFail(err)}
+	var dnsErr *net.DNSError
+	var rcodeErr *RcodeError
+	if errors.As(err, &dnsErr) {
+		resolveErr.NotFound = dnsErr.IsNotFound
+		resolveErr.Timeout = dnsErr.IsTimeout
+	} else if errors.As(err, &rcodeErr) {
+		resolveErr.NotFound = rcodeErr.Rcode == dns.RcodeNameError
+	} else if netErr, ok := err.(net.Error); ok {
+		resolveErr.Timeout = netErr.Timeout()
+	}
+	return resolveErr
+}
+
+// the Go resolver reports SERVFAIL as a temporary "server misbehaving" error,
+// and the other failure rcodes (e.g. REFUSED) as a permanent one
+func isServFail(err error) bool {
+	var dnsErr *net.DNSError
+	var rcodeErr *RcodeError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.Err == "server misbehaving" && dnsErr.IsTemporary
+	}
+	return errors.As(err, &rcodeErr) && rcodeErr.Rcode == dns.RcodeServerFailure
+}
+
+// whether `result` failed with SERVFAIL
+func isServFailResult(result *ResolveResult) bool {
+	var resolveErr *ResolveError
+	return errors.As(result.Err, &resolveErr) && resolveErr.ServFail
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("failed to resolve %s: %s", e.Hostname, e.Err.Error())
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// A response with a non-success rcode, from queries made via the raw client
+type RcodeError struct {
+	Rcode int
+}
+
+func (e *RcodeError) Error() string {
+	return fmt.Sprintf("server responded %s", dns.RcodeToString[e.Rcode])
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// expected addresses keyed by (lowercased, unqualified) hostname, from `-expect-file`
+type expectations struct {
+	label     string   // leads the overall PASS/FAIL line
+	hostnames []string // in file order
+	addrs     map[string][]string
+}
+
+func expectKey(hostname string) string {
+	return strings.ToLower(strings.TrimSuffix(hostname, "."))
+}
+
+// read `hostname expected_ip [expected_ip ...]` lines; a hostname may be
+// repeated to expect several addresses. Blank lines and `#` comments are skipped
+func loadExpectFile(path string) (*expectations, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	e := &expectations{label: "Expectations", addrs: map[string][]string{}}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected 'hostname expected_ip'", lineNo)
+		}
+
+		key := expectKey(fields[0])
+		if _, ok := e.addrs[key]; !ok {
+			e.hostnames = append(e.hostnames, fields[0])
+		}
+		for _, addr := range fields[1:] {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return nil, fmt.Errorf("line %d: invalid ip address '%s'", lineNo, addr)
+			}
+			e.addrs[key] = append(e.addrs[key], sortedIPStrings([]net.IP{ip})...)
+		}
+	}
+	return e, scanner.Err()
+}
+
+// Log PASS/FAIL for each expected hostname: it passes when every expected
+// address is among those resolved. Returns the number that failed, including
+// expected hostnames that weren't queried
+func (e *expectations) check(results []*ResolveResult) int {
+	resultsByKey := map[string]*ResolveResult{}
+	for _, result := range results {
+		resultsByKey[expectKey(result.Hostname)] = result
+	}
+
+	failed := 0
+	for _, hostname := range e.hostnames {
+		expected := e.addrs[expectKey(hostname)]
+		result, ok := resultsByKey[expectKey(hostname)]
+		switch {
+		case !ok:
+			LogError("FAIL %s: expected %s, but it wasn't queried\n", hostname, strings.Join(expected, ", "))
+			failed++
+		case result.Err != nil:
+			LogError("FAIL %s: expected %s, but it failed to resolve ('%s')\n", hostname, strings.Join(expected, ", "), shortError(result.Err))
+			failed++
+		default:
+			resolved := sortedIPStrings(result.IPs)
+			if missing, _ := diffIPSets(expected, resolved); len(missing) > 0 {
+				LogError("FAIL %s: expected %s, missing %s (resolved %s)\n", hostname, strings.Join(expected, ", "), strings.Join(missing, ", "), strings.Join(resolved, ", "))
+				failed++
+			} else {
+				LogInfo("PASS %s: %s\n", hostname, strings.Join(expected, ", "))
+			}
+		}
+	}
+
+	if failed > 0 {
+		LogError("%s: FAIL (%d of %d passed)\n", e.label, len(e.hostnames)-failed, len(e.hostnames))
+	} else {
+		LogSummary("%s: PASS (%d of %d passed)\n", e.label, len(e.hostnames), len(e.hostnames))
+	}
+	return failed
+}
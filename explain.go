@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// narrate a step of the resolution flow for `-explain`; logged even when the
+// regular per-hostname output is suppressed
+func (r *Resolver) explainf(msg string, args ...interface{}) {
+	if r.explain && !r.noLog {
+		LogInfo("EXPLAIN: "+msg+"\n", args...)
+	}
+}
+
+// describe how each configured server will be queried
+func (r *Resolver) explainServers() {
+	for _, ns := range r.servers {
+		if ns.addr == "" {
+			r.explainf("No DNS server provided; using the system's default resolver (per /etc/resolv.conf)")
+			continue
+		}
+		r.explainf("Parsed DNS server %s... valid. Building a Go resolver (preferring pure-Go) sending its queries to %s over udp", ns.addr, ns.hostPort())
+	}
+	if len(r.servers) > 1 {
+		r.explainf("%d servers configured; each query fails over to the next server on a server failure (but not on 'no such host')", len(r.servers))
+	}
+}
+
+// the record types queried for `network`, e.g. "A and AAAA"
+func queryTypeNames(network NetworkString) string {
+	var names []string
+	for _, qtype := range queryTypes(network) {
+		names = append(names, dns.TypeToString[qtype])
+	}
+	return strings.Join(names, " and ")
+}
+
+// the PTR name queried for the reverse lookup of `ip`
+func reverseQueryName(ip string) string {
+	name, err := dns.ReverseAddr(ip)
+	if err != nil {
+		return fmt.Sprintf("(reverse of %s)", ip)
+	}
+	return name
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// a failed hostname, as printed by `-failures-only -failures-format json`
+type failedHostname struct {
+	Hostname string `json:"hostname"`
+	Error    string `json:"error"`
+}
+
+// print just the failed hostnames, one per line or as a JSON array, for feeding into other tools
+func writeFailures(w io.Writer, results []*ResolveResult, failed func(*ResolveResult) bool, asJSON bool) error {
+	failures := []failedHostname{}
+	for _, result := range results {
+		if !failed(result) {
+			continue
+		}
+		failure := failedHostname{Hostname: result.Hostname}
+		if result.Err != nil {
+			failure.Error = result.Err.Error()
+		} else if len(result.ReverseErrs) > 0 {
+			failure.Error = result.ReverseErrs[0].Error()
+		} else if result.LatencyErr != nil {
+			failure.Error = result.LatencyErr.Error()
+		} else {
+			failure.Error = result.TTLErr.Error()
+		}
+		failures = append(failures, failure)
+	}
+
+	if asJSON {
+		data, err := json.Marshal(failures)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	for _, failure := range failures {
+		if _, err := fmt.Fprintln(w, failure.Hostname); err != nil {
+			return err
+		}
+	}
+	return nil
+}
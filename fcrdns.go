@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// Forward-confirm the reverse names of each of the result's addresses
+// (FCrDNS), as mail servers check for reputation: an address MATCHes when
+// one of its PTR names resolves back to it, and is a MISMATCH otherwise,
+// including when it has no PTR name at all
+func (r *Resolver) checkFCrDNS(ctx context.Context, result *ResolveResult) {
+	if !r.fcrdns || result.Err != nil {
+		return
+	}
+	hostname := r.displayName(result.Hostname)
+	for _, ip := range result.IPs {
+		if !inFamily(ip, r.reverseFamily) {
+			continue
+		}
+		names := result.Reverse[ip.String()]
+		if len(names) == 0 {
+			r.logWarning("FCrDNS for %s (%s): MISMATCH, no reverse name\n", hostname, ip)
+			continue
+		}
+		var forward []string
+		matched := ""
+		for _, name := range names {
+			ips, _, err := r.lookupIP(ctx, IP, name)
+			if err != nil {
+				forward = append(forward, name+" ("+shortError(err)+")")
+				continue
+			}
+			if slices.ContainsFunc(ips, ip.Equal) {
+				matched = name
+				break
+			}
+			forward = append(forward, name+" -> ["+addrString(ips)+"]")
+		}
+		if matched != "" {
+			r.logInfo("FCrDNS for %s (%s): MATCH via %s\n", hostname, ip, matched)
+		} else {
+			r.logWarning("FCrDNS for %s (%s): MISMATCH, %s\n", hostname, ip, strings.Join(forward, ", "))
+		}
+	}
+}
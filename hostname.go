@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// split a pasted `host:port` (or `[ipv6]:port`) into the host and port; a bare
+// IPv6 address has too many colons to split, so is left alone
+func splitHostPort(hostname string) (string, string, bool) {
+	if !strings.Contains(hostname, ":") {
+		return hostname, "", false
+	}
+	host, port, err := net.SplitHostPort(hostname)
+	if err != nil || host == "" {
+		return hostname, "", false
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return hostname, "", false
+	}
+	return host, port, true
+}
+
+// `hostname` as a plain name, without a pasted `:port` or the trailing dot,
+// for the outputs naming hosts rather than the hostnames as given
+func plainHostname(hostname string) string {
+	host, _, _ := splitHostPort(hostname)
+	return strings.TrimSuffix(host, ".")
+}
+
+// append `.domain` to `hostname` unless it's already fully qualified (trailing dot)
+// or already ends in `domain`
+func appendDomain(hostname, domain string) string {
+	domain = strings.Trim(domain, ".")
+	if domain == "" || strings.HasSuffix(hostname, ".") {
+		return hostname
+	}
+	lower := strings.ToLower(hostname)
+	suffix := strings.ToLower(domain)
+	if lower == suffix || strings.HasSuffix(lower, "."+suffix) {
+		return hostname
+	}
+	return hostname + "." + domain
+}
+
+// DNS limits on name lengths, in octets (RFC 1035)
+const (
+	maxLabelLength = 63
+	maxNameLength  = 255 // on the wire, including the length octets and the root label
+)
+
+// reject hostnames that can't be queried: empty or over-long labels, names
+// too long overall, malformed IDN (`xn--`) labels, and wildcard (`*`) labels unless `allowWildcard` is set,
+// in which case only a leftmost `*` label is accepted. The lengths of Unicode
+// labels are those of their ACE form, which goes on the wire
+func validateHostname(hostname string, allowWildcard bool) error {
+	name := strings.TrimSuffix(hostname, ".")
+	if name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidHostname)
+	}
+
+	labels := strings.Split(name, ".")
+	wireLength := 1 // root label
+	for i, label := range labels {
+		if label == "" {
+			return fmt.Errorf("%w: empty label in '%s'", ErrInvalidHostname, hostname)
+		}
+		alabel, err := aceLabel(label)
+		if err != nil {
+			return err
+		}
+		if len(alabel) > maxLabelLength {
+			return fmt.Errorf("%w: label too long (%d octets, max %d): '%s'", ErrInvalidHostname, len(alabel), maxLabelLength, label)
+		}
+		if _, err := unicodeLabel(label); err != nil {
+			return err
+		}
+		if strings.Contains(label, "*") {
+			if !allowWildcard {
+				return fmt.Errorf("%w: wildcard label in '%s' (see -allow-wildcard)", ErrInvalidHostname, hostname)
+			}
+			if label != "*" || i != 0 {
+				return fmt.Errorf("%w: wildcard must be the leftmost label on its own: '%s'", ErrInvalidHostname, hostname)
+			}
+		}
+		wireLength += len(alabel) + 1
+	}
+	if wireLength > maxNameLength {
+		return fmt.Errorf("%w: name too long (%d octets, max %d)", ErrInvalidHostname, wireLength, maxNameLength)
+	}
+	return nil
+}
+
+// the Go resolver refuses to query names containing `*`; a wildcard record
+// answers for any label though, so probe with a random label in its place; the
+// label is longer than the `*`, so the probe can exceed the name length limit
+func wildcardProbeName(hostname string) (string, error) {
+	if !strings.HasPrefix(hostname, "*.") {
+		return hostname, nil
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	probe := "wildcard-probe-" + hex.EncodeToString(b) + strings.TrimPrefix(hostname, "*")
+	if err := validateHostname(probe, false); err != nil {
+		return "", fmt.Errorf("can't probe for a wildcard record: %w", err)
+	}
+	return probe, nil
+}
+
+// parse the comma-separated `-reverse-ignore-suffix` list; a leading `*.`
+// and any trailing dot are ignored, so `*.example.com.` and `example.com` are equivalent
+func parseSuffixList(list string) []string {
+	var suffixes []string
+	for _, suffix := range strings.Split(list, ",") {
+		suffix = strings.ToLower(strings.Trim(strings.TrimPrefix(strings.TrimSpace(suffix), "*."), "."))
+		if suffix != "" {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	return suffixes
+}
+
+// whether `name` is one of `suffixes` or a subdomain of one of them (case-insensitive)
+func matchesSuffix(name string, suffixes []string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, suffix := range suffixes {
+		if name == suffix || strings.HasSuffix(name, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// a name of labels of the given lengths
+func nameOfLabels(lengths ...int) string {
+	labels := make([]string, len(lengths))
+	for i, n := range lengths {
+		labels[i] = strings.Repeat("a", n)
+	}
+	return strings.Join(labels, ".")
+}
+
+func TestValidateHostname(t *testing.T) {
+	tests := []struct {
+		name          string
+		hostname      string
+		allowWildcard bool
+		valid         bool
+	}{
+		{"63-octet label", nameOfLabels(63, 3), false, true},
+		{"64-octet label", nameOfLabels(64, 3), false, false},
+		// 3*(63+1) + (61+1) + the root label
+		{"255-octet name", nameOfLabels(63, 63, 63, 61), false, true},
+		{"255-octet name, fully qualified", nameOfLabels(63, 63, 63, 61) + ".", false, true},
+		{"256-octet name", nameOfLabels(63, 63, 63, 62), false, false},
+		{"empty name", "", false, false},
+		{"root only", ".", false, false},
+		{"empty label", "a..example", false, false},
+		{"wildcard, not allowed", "*.example.com", false, false},
+		{"wildcard", "*.example.com", true, true},
+		{"wildcard, not leftmost", "www.*.example.com", true, false},
+		{"wildcard within a label", "w*.example.com", true, false},
+		{"wildcard only", "*", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHostname(tt.hostname, tt.allowWildcard)
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidHostname) {
+				t.Errorf("got %v, want ErrInvalidHostname", err)
+			}
+		})
+	}
+}
+
+func TestWildcardProbeName(t *testing.T) {
+	probe, err := wildcardProbeName("*.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(probe, "wildcard-probe-") || !strings.HasSuffix(probe, ".example.com") {
+		t.Errorf("got %s, want a random label in place of the wildcard", probe)
+	}
+	if err := validateHostname(probe, false); err != nil {
+		t.Errorf("probe %s isn't valid: %v", probe, err)
+	}
+	if other, _ := wildcardProbeName("*.example.com"); other == probe {
+		t.Errorf("got the same probe name twice: %s", probe)
+	}
+
+	if name, err := wildcardProbeName("www.example.com"); err != nil || name != "www.example.com" {
+		t.Errorf("got %s, %v for a name without a wildcard", name, err)
+	}
+
+	// valid with the `*`, but not once it's replaced with the longer label
+	long := "*." + nameOfLabels(63, 63, 63, 59)
+	if err := validateHostname(long, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := wildcardProbeName(long); !errors.Is(err, ErrInvalidHostname) {
+		t.Errorf("got %v for an over-long probe, want ErrInvalidHostname", err)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Write the results as /etc/hosts-style `<ip> <hostname>` lines, in input
+// order, with either each hostname's first address or all of them; failed
+// hostnames are skipped, with an optional comment header
+func writeHosts(w io.Writer, results []*ResolveResult, allAddrs bool, header bool) error {
+	bw := bufio.NewWriter(w)
+	if header {
+		fmt.Fprintf(bw, "# generated by resolve-hostname at %s\n", time.Now().Format(time.RFC3339))
+	}
+	for _, result := range results {
+		if result.Err != nil || len(result.IPs) == 0 {
+			continue
+		}
+		ips := result.IPs
+		if !allAddrs {
+			ips = ips[:1]
+		}
+		// entries are plain hostnames, never with a pasted port or the trailing dot
+		hostname := plainHostname(result.Hostname)
+		for _, ip := range ips {
+			fmt.Fprintf(bw, "%s\t%s\n", ipString(ip), hostname)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// a label mixes scripts (e.g. Latin and Cyrillic), as is common in homograph spoofs
+var ErrMixedScripts = errors.New("mixed scripts")
+
+const acePrefix = "xn--"
+
+// the script names, sorted so that lookups are deterministic
+var scriptNames = func() []string {
+	names := make([]string, 0, len(unicode.Scripts))
+	for name := range unicode.Scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}()
+
+// the Unicode form of an IDN label ("xn--..."), validated per IDNA2008;
+// other labels are returned as is
+func unicodeLabel(label string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(label), acePrefix) {
+		return label, nil
+	}
+	ulabel, err := idna.Lookup.ToUnicode(label)
+	if err == nil && ulabel == "" {
+		err = errors.New("empty label")
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed IDN label '%s': %s", ErrInvalidHostname, label, err.Error())
+	}
+	return ulabel, nil
+}
+
+// the ACE ("xn--...") form of a Unicode label, as it goes on the wire;
+// ASCII labels are returned as is
+func aceLabel(label string) (string, error) {
+	ascii := true
+	for i := 0; i < len(label); i++ {
+		if label[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return label, nil
+	}
+	alabel, err := idna.Lookup.ToASCII(label)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid IDN label '%s': %s", ErrInvalidHostname, label, err.Error())
+	}
+	return alabel, nil
+}
+
+// the combinations of scripts a label may mix, as in UTS #39's "highly
+// restrictive" level: Japanese, Chinese with Bopomofo and Korean, each with
+// Latin; any other mix is flagged
+var allowedScriptSets = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// whether a label with letters from `scripts` mixes scripts in a way that
+// isn't allowed (see `allowedScriptSets`)
+func mixedScripts(scripts []string) bool {
+	if len(scripts) <= 1 {
+		return false
+	}
+	for _, allowed := range allowedScriptSets {
+		if subsetOf(scripts, allowed) {
+			return false
+		}
+	}
+	return true
+}
+
+// whether every one of `items` is in `set`
+func subsetOf(items, set []string) bool {
+	for _, item := range items {
+		if !slices.Contains(set, item) {
+			return false
+		}
+	}
+	return true
+}
+
+// the scripts of the letters in `label`; digits, hyphens and other
+// characters common to all scripts don't count
+func labelScripts(label string) []string {
+	seen := map[string]bool{}
+	var scripts []string
+	for _, c := range label {
+		if !unicode.IsLetter(c) {
+			continue
+		}
+		for _, name := range scriptNames {
+			if name == "Common" || name == "Inherited" || !unicode.Is(unicode.Scripts[name], c) {
+				continue
+			}
+			if !seen[name] {
+				seen[name] = true
+				scripts = append(scripts, name)
+			}
+			break
+		}
+	}
+	return scripts
+}
+
+// Check the Unicode form of each IDN label of `hostname` for letters from
+// more than one script (other than the combinations in `allowedScriptSets`),
+// returning the first such label and its scripts; plain ASCII hostnames
+// aren't checked
+func checkMixedScripts(hostname string) error {
+	for _, label := range strings.Split(strings.TrimSuffix(hostname, "."), ".") {
+		ulabel, err := unicodeLabel(label)
+		if err != nil {
+			return err
+		}
+		if scripts := labelScripts(ulabel); mixedScripts(scripts) {
+			return fmt.Errorf("%w in label '%s' ('%s'): %s", ErrMixedScripts, label, ulabel, strings.Join(scripts, ", "))
+		}
+	}
+	return nil
+}
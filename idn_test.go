@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnicodeLabel(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"www", "www"},
+		{"_dmarc", "_dmarc"},
+		{"xn--mnchen-3ya", "münchen"},
+		{"XN--MNCHEN-3YA", "münchen"},
+		{"xn--pple-43d", "аpple"},
+		{"xn--55qx5d", "公司"},
+	}
+	for _, tt := range tests {
+		got, err := unicodeLabel(tt.label)
+		if err != nil || got != tt.want {
+			t.Errorf("unicodeLabel(%s) = %q, %v; want %q", tt.label, got, err, tt.want)
+		}
+	}
+
+	for _, label := range []string{"xn--", "xn--a", "xn--abc", "xn--zzzzzzzzzzz9"} {
+		if got, err := unicodeLabel(label); !errors.Is(err, ErrInvalidHostname) {
+			t.Errorf("unicodeLabel(%s) = %q, %v; want ErrInvalidHostname", label, got, err)
+		}
+	}
+}
+
+func TestCheckMixedScripts(t *testing.T) {
+	tests := []struct {
+		hostname string
+		mixed    bool
+	}{
+		{"www.example.com", false},
+		{"xn--mnchen-3ya.de", false},
+		{"xn--55qx5d.cn", false},
+		// a Cyrillic `а` among Latin letters
+		{"xn--pple-43d.com", true},
+		{"www.xn--pple-43d.com", true},
+		// digits and hyphens are common to all scripts
+		{"xn--mnchen-2-65a.de", false},
+		// the combinations UTS #39 allows: Japanese, Korean and Chinese
+		// with Bopomofo, each with Latin
+		{"ソニーの東京.jp", false},
+		{"xn--u9jvipb5lm12l9b5a.jp", false},
+		{"sonyのショップ.jp", false},
+		{"삼성電子.kr", false},
+		{"삼성shop.kr", false},
+		{"abc中文ㄅ.tw", false},
+		{"ㄅ삼성.com", true},
+		{"ソニー삼성.com", true},
+		{"中文ㄅの.com", true},
+		{"東京аpple.com", true},
+	}
+	for _, tt := range tests {
+		err := checkMixedScripts(tt.hostname)
+		if mixed := errors.Is(err, ErrMixedScripts); mixed != tt.mixed {
+			t.Errorf("checkMixedScripts(%s) = %v; want mixed scripts %t", tt.hostname, err, tt.mixed)
+		}
+	}
+}
+
+// malformed punycode is an invalid hostname rather than a possible homograph
+func TestMalformedIDN(t *testing.T) {
+	if err := validateHostname("xn--abc.example.com", false); !errors.Is(err, ErrInvalidHostname) {
+		t.Errorf("got %v, want ErrInvalidHostname", err)
+	}
+
+	r := NewResolver()
+	r.DisableLogging()
+	r.idnStrict = true
+	result := r.ResolveHostname(t.Context(), IPv4, "xn--abc.example.com")
+	if !errors.Is(result.Err, ErrInvalidHostname) || errors.Is(result.Err, ErrMixedScripts) {
+		t.Errorf("got %v, want ErrInvalidHostname", result.Err)
+	}
+}
+
+// the length limits apply to the ACE form of a Unicode label, which goes on
+// the wire, rather than to its UTF-8
+func TestUnicodeLabelLength(t *testing.T) {
+	tests := []struct {
+		hostname string
+		valid    bool
+	}{
+		// 60 octets of UTF-8, but 66 as xn--aaa...-y9f
+		{strings.Repeat("a", 58) + "ü.de", false},
+		// 90 octets of UTF-8, but 36 as xn--fiq...
+		{strings.Repeat("中", 30) + ".cn", true},
+		// 5 labels of 90 octets of UTF-8 each, but 189 octets on the wire as ACE
+		{strings.Repeat(strings.Repeat("中", 30)+".", 5) + "cn", true},
+	}
+	for _, tt := range tests {
+		err := validateHostname(tt.hostname, false)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateHostname(%s) = %v; want valid %t", tt.hostname, err, tt.valid)
+		}
+	}
+}
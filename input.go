@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// flag.Value collecting each occurrence of a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// the `-input-format` readers, each reading the hostnames from an input file;
+// `column` names the CSV column holding them
+var inputFormats = map[string]func(r io.Reader, column string) ([]string, error){
+	"text": func(r io.Reader, column string) ([]string, error) {
+		return readHostnamesText(r)
+	},
+	"json": func(r io.Reader, column string) ([]string, error) {
+		return readHostnamesJSON(r)
+	},
+	"csv": readHostnamesCSV,
+}
+
+// read the hostnames from the file at `path` in `format` (see `inputFormats`)
+func readHostnamesFile(path, format, column string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return inputFormats[format](f, column)
+}
+
+// read one hostname per line; blank lines and `#` comments are skipped
+func readHostnamesText(r io.Reader) ([]string, error) {
+	var hostnames []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line != "" {
+			hostnames = append(hostnames, line)
+		}
+	}
+	return hostnames, scanner.Err()
+}
+
+// read a JSON array of hostnames; a malformed file is reported with the
+// line and column of the error
+func readHostnamesJSON(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var hostnames []string
+	if err := json.Unmarshal(data, &hostnames); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return nil, fmt.Errorf("%s: %w", position(data, syntaxErr.Offset), err)
+		case errors.As(err, &typeErr):
+			return nil, fmt.Errorf("%s: expected an array of hostnames: %w", position(data, typeErr.Offset), err)
+		}
+		return nil, err
+	}
+	// blank entries are skipped, as blank lines are
+	var names []string
+	for _, hostname := range hostnames {
+		if hostname = strings.TrimSpace(hostname); hostname != "" {
+			names = append(names, hostname)
+		}
+	}
+	return names, nil
+}
+
+// the line and column (both from 1) of the byte `offset` into `data`
+func position(data []byte, offset int64) string {
+	before := data[:min(int(offset), len(data))]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return fmt.Sprintf("line %d, column %d", line, column)
+}
+
+// read the hostnames from the `column` of a CSV file whose first row is the
+// header; blank cells are skipped. The parse errors (from `csv.ParseError`)
+// include the line and column
+func readHostnamesCSV(r io.Reader, column string) ([]string, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("no '%s' column in the header (%s)", column, strings.Join(header, ", "))
+	}
+
+	var hostnames []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return hostnames, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if hostname := strings.TrimSpace(record[index]); hostname != "" {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+}
+
+// the hostnames matching `filter`, in order
+func filterHostnames(hostnames []string, filter *regexp.Regexp) []string {
+	var matched []string
+	for _, hostname := range hostnames {
+		if filter.MatchString(hostname) {
+			matched = append(matched, hostname)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Run `cycle` immediately and then every `interval` until `ctx` is done (e.g.
+// on an interrupt), returning the results of the last cycle. A cycle running
+// longer than `interval` delays the next rather than overlapping it
+func runCycles(ctx context.Context, interval time.Duration, cycle func(n int) []*ResolveResult) []*ResolveResult {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var results []*ResolveResult
+	for n := 1; ; n++ {
+		results = cycle(n)
+		select {
+		case <-ctx.Done():
+			LogInfo("Stopping after %d cycle(s)\n", n)
+			return results
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// the root servers' IPv4 addresses (as in the root hints file), where the
+// iterative resolution starts unless `-dnsserver` is given
+var rootHints = []string{
+	"198.41.0.4",     // a.root-servers.net
+	"170.247.170.2",  // b
+	"192.33.4.12",    // c
+	"199.7.91.13",    // d
+	"192.203.230.10", // e
+	"192.5.5.241",    // f
+	"192.112.36.4",   // g
+	"198.97.190.53",  // h
+	"192.36.148.17",  // i
+	"192.58.128.30",  // j
+	"193.0.14.129",   // k
+	"199.7.83.42",    // l
+	"202.12.27.33",   // m
+}
+
+// bounds on resolving a single hostname iteratively: the queries sent in all
+// (including for the nameservers' addresses and the CNAME targets), and how
+// deeply the nameserver lookups nest
+const (
+	maxIterativeQueries = 100
+	maxIterativeDepth   = 4
+)
+
+var errIterativeLimit = errors.New("too many queries resolving iteratively")
+
+// the state of resolving a single hostname iteratively
+type iteration struct {
+	r       *Resolver
+	ctx     context.Context
+	queries int
+}
+
+// Resolve `hostname` ourselves, as a recursive resolver would: starting at
+// the root servers (or the `-dnsserver` addresses, e.g. a lab's root), each
+// query is sent without recursion and followed down the referrals to the
+// authoritative servers, logging each delegation. With `-qname-minimization`
+// (RFC 7816), each server is only sent the labels needed to find the next
+// zone cut, as NS queries, rather than the full name. No reverse lookups are
+// performed, as those would need a recursive resolver
+func (r *Resolver) ResolveIterative(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	it := &iteration{r: r, ctx: ctx}
+	var ips []net.IP
+	var answeredBy []string
+	for _, qtype := range queryTypes(network) {
+		addrs, server, err := it.resolve(dns.Fqdn(hostname), qtype, 0)
+		if err != nil {
+			r.logError("Failed to resolve: %s iteratively (%s): Error - '%s'\n", hostname, dns.TypeToString[qtype], shortError(err))
+			result.Err = newResolveError(hostname, err)
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		ips = append(ips, addrs...)
+		if server != "" && (len(answeredBy) == 0 || answeredBy[len(answeredBy)-1] != server) {
+			answeredBy = append(answeredBy, server)
+		}
+	}
+	result.Duration = time.Since(startTime)
+	result.Server = strings.Join(answeredBy, ", ")
+	if len(ips) == 0 {
+		r.logError("Failed to resolve: %s Error - '%s'", hostname, ErrNoAddresses.Error())
+		result.Err = newResolveError(hostname, ErrNoAddresses)
+		return result
+	}
+	if !r.rawIPv6 {
+		ips = unmapIPv4(ips)
+	}
+	result.IPs = ips
+	r.logInfo("IP addresses for hostname '%s' via %s (iteratively, %d queries): %v\n", r.displayName(hostname), result.Server, it.queries, r.limitAddrs(ips))
+	return result
+}
+
+// the servers the iteration starts at
+func (it *iteration) startServers() []string {
+	var servers []string
+	for _, ns := range it.r.servers {
+		if ns.addr != "" {
+			servers = append(servers, ns.addr)
+		}
+	}
+	if len(servers) == 0 {
+		return rootHints
+	}
+	return servers
+}
+
+// Resolve `name` `qtype` from the root, returning the addresses answered (none
+// for NODATA) and the server answering; `depth` is how deeply this lookup is
+// nested within others, for a nameserver's address
+func (it *iteration) resolve(name string, qtype uint16, depth int) ([]net.IP, string, error) {
+	zone := "."
+	servers := it.startServers()
+	labels := dns.CountLabel(name)
+	// with QNAME minimization, the number of labels of the name to query next
+	next := 1
+	for {
+		qname, qt := name, qtype
+		if it.r.qnameMinimization && next < labels {
+			qname, qt = lastLabels(name, next), dns.TypeNS
+		}
+
+		resp, server, err := it.query(servers, qname, qt)
+		if err != nil {
+			return nil, server, err
+		}
+
+		if child, nsNames := referral(resp, zone, name); child != "" {
+			glue := glueAddrs(resp.Extra, nsNames)
+			glueStr := "no glue"
+			if len(glue) > 0 {
+				glueStr = "glue " + strings.Join(glue, ", ")
+			}
+			it.r.logInfo("Delegation for %s: %s -> %s, served by %s (%s), from %s\n", it.r.displayName(name), zone, child, strings.Join(nsNames, ", "), glueStr, server)
+			if len(glue) == 0 {
+				if glue, err = it.nameServerAddrs(nsNames, depth); err != nil {
+					return nil, server, fmt.Errorf("no address for the nameservers of %s: %w", child, err)
+				}
+			}
+			zone, servers, next = child, glue, dns.CountLabel(child)+1
+			continue
+		}
+
+		if qname != name {
+			// no zone cut at `qname` (or one served by the same servers), so
+			// add a label and ask the same servers
+			it.r.logDebug("No delegation at %s from %s; adding a label\n", qname, server)
+			if hasRecordsFor(qname, dns.TypeNS, resp.Answer) {
+				zone = qname
+			}
+			next++
+			continue
+		}
+
+		if addrs := addrsFromRRs(resp.Answer); len(addrs) > 0 {
+			it.r.logDebug("Answer for %s %s from %s (authoritative: %t): %s\n", name, dns.TypeToString[qtype], server, resp.Authoritative, rrStrings(resp.Answer))
+			return addrs, server, nil
+		}
+		if chain := cnameChain(name, resp.Answer); len(chain) > 0 {
+			target := chain[len(chain)-1]
+			it.r.logInfo("CNAME for %s: %s -> %s, resolving the target from the root\n", it.r.displayName(name), it.r.displayName(name), it.r.displayName(target))
+			return it.resolve(target, qtype, depth)
+		}
+		it.r.logDebug("No %s records for %s at %s\n", dns.TypeToString[qtype], name, server)
+		return nil, server, nil
+	}
+}
+
+// Send `qname` `qtype` without recursion to each of `servers` in turn until
+// one answers; NXDOMAIN is an answer, returned as an `*RcodeError`
+func (it *iteration) query(servers []string, qname string, qtype uint16) (*dns.Msg, string, error) {
+	var lastErr error
+	for _, addr := range servers {
+		if it.queries >= maxIterativeQueries {
+			return nil, addr, errIterativeLimit
+		}
+		it.queries++
+		msg := new(dns.Msg)
+		msg.SetQuestion(qname, qtype)
+		msg.RecursionDesired = false
+		it.r.logDebug("Querying %s for %s %s\n", addr, qname, dns.TypeToString[qtype])
+		resp, err := it.r.exchange(it.ctx, nameServer{addr: addr}, msg)
+		var rcodeErr *RcodeError
+		if err == nil || (errors.As(err, &rcodeErr) && rcodeErr.Rcode == dns.RcodeNameError) {
+			return resp, addr, err
+		}
+		it.r.logWarning("Query to %s for %s %s failed: '%s'\n", addr, qname, dns.TypeToString[qtype], shortError(err))
+		lastErr = err
+		if it.ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, "", lastErr
+}
+
+// the addresses of the first of `nsNames` that resolves iteratively, for a
+// delegation without glue
+func (it *iteration) nameServerAddrs(nsNames []string, depth int) ([]string, error) {
+	if depth >= maxIterativeDepth {
+		return nil, errIterativeLimit
+	}
+	var lastErr error
+	for _, nsName := range nsNames {
+		ips, _, err := it.resolve(nsName, dns.TypeA, depth+1)
+		if err == nil && len(ips) == 0 {
+			err = ErrNoAddresses
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		addrs := make([]string, len(ips))
+		for i, ip := range ips {
+			addrs[i] = ip.String()
+		}
+		return addrs, nil
+	}
+	return nil, lastErr
+}
+
+// The zone `resp` delegates to and the names of its nameservers, when it's a
+// referral from `zone` towards `name`; the zone must be below `zone` and
+// `name` within it, so a server can't send the iteration sideways or back up
+func referral(resp *dns.Msg, zone, name string) (string, []string) {
+	if len(resp.Answer) > 0 {
+		return "", nil
+	}
+	var child string
+	var nsNames []string
+	for _, rr := range resp.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		owner := dns.Fqdn(ns.Hdr.Name)
+		if strings.EqualFold(owner, zone) || !dns.IsSubDomain(zone, owner) || !dns.IsSubDomain(owner, name) {
+			continue
+		}
+		if child != "" && !strings.EqualFold(owner, child) {
+			continue
+		}
+		child = owner
+		nsNames = append(nsNames, ns.Ns)
+	}
+	return child, nsNames
+}
+
+// the IPv4 addresses (then the IPv6 ones) in the glue `rrs` for `nsNames`
+func glueAddrs(rrs []dns.RR, nsNames []string) []string {
+	var v4, v6 []string
+	for _, rr := range rrs {
+		owner := rr.Header().Name
+		known := false
+		for _, nsName := range nsNames {
+			if strings.EqualFold(owner, nsName) {
+				known = true
+			}
+		}
+		if !known {
+			continue
+		}
+		switch rec := rr.(type) {
+		case *dns.A:
+			v4 = append(v4, rec.A.String())
+		case *dns.AAAA:
+			v6 = append(v6, rec.AAAA.String())
+		}
+	}
+	return append(v4, v6...)
+}
+
+// the last `n` labels of `name`, as a fully qualified name
+func lastLabels(name string, n int) string {
+	indexes := dns.Split(name)
+	return name[indexes[len(indexes)-n]:]
+}
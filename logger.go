@@ -5,15 +5,48 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"sync/atomic"
 )
 
 type logger struct {
 	infoLogger  *log.Logger
 	errorLogger *log.Logger
+	debugPrefix string
+	infoPrefix  string
+	warnPrefix  string
+	errorPrefix string
+	level       logLevel // messages below this level are dropped
+	// the summary line is logged at INFO, unless suppressed by the level
+	alwaysSummary bool
 }
 
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+// the `-verbosity` names of the levels
+var logLevels = map[string]logLevel{"debug": levelDebug, "info": levelInfo, "warn": levelWarn, "error": levelError}
+
+// the level prefixes used unless configured otherwise
+const (
+	defaultDebugPrefix = "DEBUG: "
+	defaultInfoPrefix  = "INFO: "
+	defaultWarnPrefix  = "WARN: "
+	defaultErrorPrefix = "ERROR: "
+)
+
 var globalLogger *logger
 
+// the last error logged (without its prefix), e.g. for `-nagios`'s status
+// line when the run exits early
+var lastError atomic.Pointer[string]
+
 func InitializeLogger() {
 	// file options (Llongfile Lshortfile) are nice but useless for this wrapper
 	// as we'd end up with `logger.go : line`
@@ -21,7 +54,53 @@ func InitializeLogger() {
 	globalLogger = &logger{
 		infoLogger:  log.New(os.Stdout, "", flags),
 		errorLogger: log.New(os.Stderr, "", flags),
+		level:       levelInfo,
+		debugPrefix: defaultDebugPrefix,
+		infoPrefix:  defaultInfoPrefix,
+		warnPrefix:  defaultWarnPrefix,
+		errorPrefix: defaultErrorPrefix,
+	}
+}
+
+// Replace the level prefixes (e.g. "INFO: "); empty strings remove them
+func SetLogPrefixes(debugPrefix, infoPrefix, warnPrefix, errorPrefix string) {
+	maybeInitializeLogger()
+	globalLogger.debugPrefix = debugPrefix
+	globalLogger.infoPrefix = infoPrefix
+	globalLogger.warnPrefix = warnPrefix
+	globalLogger.errorPrefix = errorPrefix
+}
+
+// Only log messages at or above `level` (debug|info|warn|error); returns false for
+// an unknown level
+func SetLogVerbosity(level string) bool {
+	maybeInitializeLogger()
+	l, ok := logLevels[level]
+	if ok {
+		globalLogger.level = l
+	}
+	return ok
+}
+
+// Log summary lines even when the verbosity suppresses INFO messages
+func SetAlwaysSummary(always bool) {
+	maybeInitializeLogger()
+	globalLogger.alwaysSummary = always
+}
+
+// Log the info (and debug) messages to stderr along with the warnings and
+// errors, for when stdout carries only the run's machine-readable output
+func SetInfoToStderr() {
+	maybeInitializeLogger()
+	globalLogger.infoLogger = globalLogger.errorLogger
+}
+
+// the last error logged, if any
+func LastError() string {
+	if last := lastError.Load(); last != nil {
+		return *last
 	}
+	return ""
 }
 
 func maybeInitializeLogger() {
@@ -34,15 +113,49 @@ func formatLogMessage(prefix, format string, args ...interface{}) string {
 	return fmt.Sprintf("%s%s", prefix, fmt.Sprintf(format, args...))
 }
 
+// details for debugging, only logged with the verbosity lowered to debug
+func LogDebug(msg string, args ...interface{}) {
+	maybeInitializeLogger()
+	if globalLogger.level > levelDebug {
+		return
+	}
+	formattedMessage := formatLogMessage(globalLogger.debugPrefix, msg, args...)
+	globalLogger.infoLogger.Print(formattedMessage)
+}
+
 func LogInfo(msg string, args ...interface{}) {
 	// we'll allow the initialization to be overlooked
 	maybeInitializeLogger()
-	formattedMessage := formatLogMessage("INFO: ", msg, args...)
-	globalLogger.infoLogger.Printf(formattedMessage)
+	if globalLogger.level > levelInfo {
+		return
+	}
+	formattedMessage := formatLogMessage(globalLogger.infoPrefix, msg, args...)
+	globalLogger.infoLogger.Print(formattedMessage)
+}
+
+// an INFO message summarizing a run, which `SetAlwaysSummary` keeps regardless of the verbosity
+func LogSummary(msg string, args ...interface{}) {
+	maybeInitializeLogger()
+	if globalLogger.level > levelInfo && !globalLogger.alwaysSummary {
+		return
+	}
+	formattedMessage := formatLogMessage(globalLogger.infoPrefix, msg, args...)
+	globalLogger.infoLogger.Print(formattedMessage)
+}
+
+func LogWarning(msg string, args ...interface{}) {
+	maybeInitializeLogger()
+	if globalLogger.level > levelWarn {
+		return
+	}
+	formattedMessage := formatLogMessage(globalLogger.warnPrefix, msg, args...)
+	globalLogger.errorLogger.Print(formattedMessage)
 }
 
 func LogError(msg string, args ...interface{}) {
 	maybeInitializeLogger()
-	formattedMessage := formatLogMessage("ERROR: ", msg, args...)
-	globalLogger.errorLogger.Printf(formattedMessage)
+	last := strings.TrimSpace(fmt.Sprintf(msg, args...))
+	lastError.Store(&last)
+	formattedMessage := formatLogMessage(globalLogger.errorPrefix, msg, args...)
+	globalLogger.errorLogger.Print(formattedMessage)
 }
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestSetInfoToStderr(t *testing.T) {
+	stdout, stderr := captureLogs(t)
+	LogInfo("before\n")
+	SetInfoToStderr()
+	LogInfo("info\n")
+	LogSummary("summary\n")
+	LogError("error\n")
+
+	if got := stdout.String(); got != "INFO: before\n" {
+		t.Errorf("got %q on stdout", got)
+	}
+	if got := stderr.String(); got != "INFO: info\nINFO: summary\nERROR: error\n" {
+		t.Errorf("got %q on stderr", got)
+	}
+}
@@ -8,40 +8,157 @@ import (
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"regexp"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 const helpMsg string = `Resolve hostnames via a provided DNS address; cancel if not complete by timeout:
-Usage: resolve-hostname [-dnsserver dns-server-ip-addr] [-timeout timeout-duration-ms] <hostname1> <hostname2> ...`
+Usage: resolve-hostname [-dnsserver dns-server-ip-addr[,dns-server-ip-addr...]] [-input file [-input file ...]] [-parallel-files] [-concurrency n] [-shuffle-servers] [-seed n] [-timeout timeout-duration-ms | -deadline rfc3339-time] [-reverse-errors-fatal] [-warm] [-warm-state file] [-warm-window duration] <hostname1> <hostname2> ...`
 
-// ensure this is a valid ip address
-// we have a valid IP provided for DNS; create our resolver for this
+// exit codes for runs where hostnames failed to resolve
+const (
+	exitFailure     = 1 // at least one hostname failed
+	exitAllFailed   = 2 // no hostname resolved
+	exitServFail    = 3 // a server responded SERVFAIL, with -servfail-fatal
+	exitUnreachable = 4 // none of the -dnsserver addresses responded at startup
+)
+
+// the `-nagios` status line's writer, which every exit must go through
+var nagiosOut *nagiosWriter
+
+// exit with `code`; with `-nagios`, as the plugin's state along with its
+// status line
+func exit(code int) {
+	if nagiosOut != nil {
+		nagiosOut.exit(code)
+	}
+	os.Exit(code)
+}
+
+// log the usage and exit, for invalid arguments; with `-nagios`, the state is
+// UNKNOWN
+func fatalUsage() {
+	if nagiosOut != nil {
+		log.Print(helpMsg)
+		nagiosOut.exitUsage()
+	}
+	log.Fatalf(helpMsg)
+}
+
+// ensure these are valid ip addresses
+// we have valid IPs provided for DNS; create our resolver for these
 // otherwise, we'll use the default DNS server
-func getDnsResolver(dnsServerIp *string) (*Resolver, error) {
-	// use our `Resolver` if addr present and valid
-	if dnsServerIp != nil && len(*dnsServerIp) != 0 {
-		if !(net.ParseIP(*dnsServerIp) != nil) {
-			return nil, errors.New(fmt.Sprintf("Invalid ip address: %s", *dnsServerIp))
-		} else {
-			return NewResolver(*dnsServerIp), nil
+func getDnsResolver(dnsServerIps *string) (*Resolver, error) {
+	// use our `Resolver` if addrs present and valid
+	if dnsServerIps != nil && len(*dnsServerIps) != 0 {
+		addrs := strings.Split(*dnsServerIps, ",")
+		for i, addr := range addrs {
+			addrs[i] = strings.TrimSpace(addr)
+			if net.ParseIP(addrs[i]) == nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidDNSServer, addrs[i])
+			}
+		}
+		return NewResolver(addrs...), nil
+	}
+
+	// otherwise, use the default, noting which of the system's servers answered
+	return NewSystemResolver(), nil
+}
+
+// leads the summary line with why the run was cut short, if it was (see `cancelReason`)
+func prefixStr(reason string) string {
+	if reason != "" {
+		return reason
+	}
+	return "Total duration"
+}
+
+// hostnames resolved (and summarized) together; `label` is empty for the single unlabeled batch
+type hostnameBatch struct {
+	label     string
+	hostnames []string
+}
+
+func mustReadHostnamesFile(path, format, column string) []string {
+	hostnames, err := readHostnamesFile(path, format, column)
+	if err != nil {
+		LogError("Failed to read input file '%s': %s\n", path, err.Error())
+		exit(1)
+	}
+	return hostnames
+}
+
+func countResolved(results []*ResolveResult) int {
+	resolved := 0
+	for _, result := range results {
+		if result.Err == nil {
+			resolved++
 		}
 	}
+	return resolved
+}
 
-	// otherwise, use the default
-	return &Resolver{
-		resolver: net.DefaultResolver,
-	}, nil
+func countServFails(results []*ResolveResult) int {
+	servFails := 0
+	for _, result := range results {
+		if isServFailResult(result) {
+			servFails++
+		}
+	}
+	return servFails
 }
 
-func prefixStr(total time.Duration, timeout time.Duration) string {
-	prefixStr := ""
-	if total > timeout {
-		prefixStr = "Deadline exceeded"
+func logSummary(label string, hostnames []string, results []*ResolveResult, duration time.Duration, reason string) {
+	addrs := strings.Join(hostnames, ", ")
+
+	var addrStr string
+	if len(hostnames) > 1 {
+		addrStr = "addresses"
 	} else {
-		prefixStr = "Total duration"
+		addrStr = "address"
 	}
-	return prefixStr
+
+	labelStr := ""
+	if label != "" {
+		labelStr = "[" + label + "] "
+	}
+
+	servFailStr := ""
+	if servFails := countServFails(results); servFails > 0 {
+		servFailStr = fmt.Sprintf("; %d SERVFAIL", servFails)
+	}
+
+	resolved := countResolved(results)
+	LogSummary("%s%s for %d %s (%s): %s; %d of %d resolved%s\n", labelStr, prefixStr(reason), len(hostnames), addrStr, addrs, formatDuration(duration), resolved, len(hostnames), servFailStr)
+}
+
+// resolve the batches concurrently, summarizing each labeled batch as it completes
+// (unless `-no-summary`); results are returned in batch order
+func resolveBatches(ctx context.Context, r *Resolver, network NetworkString, batches []hostnameBatch, summarize bool) []*ResolveResult {
+	batchResults := make([][]*ResolveResult, len(batches))
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			batchResults[i] = r.ResolveHostnames(ctx, network, batch.hostnames)
+			if batch.label != "" && summarize {
+				logSummary(batch.label, batch.hostnames, batchResults[i], time.Since(start), cancelReason(ctx))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var results []*ResolveResult
+	for _, batch := range batchResults {
+		results = append(results, batch...)
+	}
+	return results
 }
 
 func validNetworkString(s string) bool {
@@ -60,48 +177,946 @@ func main() {
 	// this is a bit short by default
 	defaultTimeoutMs := 1000
 
-	dnsServerIp := flag.String("dnsserver", "", "The DNS server to use to resolve hostnames")
+	dnsServerIp := flag.String("dnsserver", "", "The DNS server to use to resolve hostnames; a comma-separated list fails over in order")
+	shuffleServers := flag.Bool("shuffle-servers", false, "Start each query at a randomly chosen server from -dnsserver (failover still covers every server)")
+	seed := flag.Int64("seed", 0, "Seed for randomized behaviour such as -shuffle-servers and the retry jitter (default: time-based)")
 	timeoutArg := flag.Int("timeout", defaultTimeoutMs, "Timeout in milliseconds")
+	interval := flag.Duration("interval", 0, "Resolve the hostnames repeatedly at this interval (e.g. '30s'), logging a summary per cycle, until interrupted")
+	deadlineArg := flag.String("deadline", "", "Absolute deadline (RFC 3339, e.g. 2026-01-02T12:00:00Z) at which to abort resolving; can't be combined with -timeout")
 	networkType := flag.String("iptype", string(IPv4), "Resolve ipv4, ipv6, or both. Must be one 'ip', 'ip4', or 'ip6' (default 'ip4')")
+	fallbackSuffix := flag.String("fallback-suffix", "", "Domain appended to a hostname whose lookup got NXDOMAIN, for a second lookup, e.g. 'example.com' retries 'host1' as 'host1.example.com'")
+	appendDomainArg := flag.String("append-domain", "", "Domain appended to each hostname not already ending in it (and not fully qualified) before lookup")
+	retries := flag.Int("retries", 0, "Number of times to retry a forward lookup that failed with a transient error")
+	servFailFatal := flag.Bool("servfail-fatal", false, "Exit with status 3 when any server responded SERVFAIL, apart from other failures")
+	retryBackoff := flag.Duration("retry-backoff", 100*time.Millisecond, "Bound for the random delay before the first retry, doubling for each further retry (0 to retry immediately)")
+	fcrdns := flag.Bool("fcrdns", false, "Forward-confirm the reverse names (FCrDNS): resolve each address's PTR names back, reporting MATCH or MISMATCH per address, e.g. for mail server reputation checks")
+	answerLimit := flag.Int("answer-limit", 0, "Show only the first n addresses (and reverse names) per hostname in the human output, followed by '(... and m more)'; the structured output keeps them all")
+	firstIP := flag.Bool("first-ip", false, "Keep only the first address for each hostname, skipping the reverse lookups (see -sort-ips)")
+	stable := flag.Bool("stable", false, "Hold back the output until the run completes, then write it sorted by hostname, with each hostname's addresses and reverse names sorted, for diffing runs")
+	durationFormat := flag.String("duration-format", "ms", "How durations are displayed: 'ms' (e.g. '123456 ms') or 'human' (e.g. '2m3s')")
+	sortKey := flag.String("sortkey", "hostname", "The order of the -stable output: "+resultSortKeyNames()+" (ties are ordered by hostname)")
+	sortIPsArg := flag.Bool("sort-ips", false, "Sort each hostname's addresses (IPv4 first, then numerically) rather than keeping the resolver's order")
+	maxFailures := flag.Int("max-failures", 0, "Abort the run (or -interval cycle) once this many hostnames have failed, e.g. when the server is down (0 for no limit)")
+	partialOK := flag.Bool("partial-ok", false, "With -iptype ip, count a hostname as resolved when either its A or AAAA lookup succeeds")
+	timeoutEscalation := flag.Duration("timeout-escalation", 0, "Time out each forward lookup's first attempt after this duration, doubling it for each retry (at most 10s; requires -retries), for fast failures that still tolerate occasional slowness")
+	retryOnEmpty := flag.Bool("retry-on-empty", false, "Also retry (subject to -retries) lookups that returned no addresses")
+	allowWildcard := flag.Bool("allow-wildcard", false, "Allow a leftmost '*' label, to test whether wildcard records exist")
+	rawIPv6 := flag.Bool("raw-ipv6", false, "Show IPv4-mapped IPv6 addresses in their mapped form (::ffff:1.2.3.4) rather than as dotted-quad")
+	reverseCIDR := flag.String("reverse-cidr", "", "Comma-separated address blocks (e.g. 192.0.2.0/24) whose every address is reverse looked up, for a reverse DNS audit")
+	idnStrict := flag.Bool("idn-strict", false, "Fail hostnames with a label mixing scripts (e.g. Latin and Cyrillic), rather than logging a warning")
+	idnAllow := flag.String("idn-allow", "", "Comma-separated domain suffixes exempt from the mixed-script check, for legitimately multilingual domains")
+	reverseIgnoreSuffix := flag.String("reverse-ignore-suffix", "", "Comma-separated domain suffixes; reverse names equal to or under these are suppressed from the output")
+	reverseFamily := flag.String("reverse-family", "both", "Only perform reverse lookups for addresses of this family: 'ip4', 'ip6', or 'both'")
+	explain := flag.Bool("explain", false, "Narrate each step of the resolution (server setup, validation, queries, reverse lookups) at INFO, for learning and debugging")
+	cacheFile := flag.String("cache-file", "", "File caching the successful lookups between runs, each until its TTL expires; the unexpired ones are served from it rather than queried again")
+	cacheReverse := flag.Bool("cache-reverse", false, "Reverse lookup each IP address only once per run, reusing the result for other hostnames sharing it")
+	reverseErrorsFatal := flag.Bool("reverse-errors-fatal", false, "Count reverse lookup failures towards the failures for the run (and the exit code)")
+	var inputFiles stringList
+	flag.Var(&inputFiles, "input", "File of hostnames to resolve, one per line ('#' comments allowed); may be repeated")
+	otelEndpoint := flag.String("otel-endpoint", "", "Export OpenTelemetry traces (a span per hostname, with child spans for the reverse lookups) to this OTLP/HTTP collector, e.g. 'http://localhost:4318'")
+	cpuProfile := flag.String("cpuprofile", "", "Write a CPU profile (pprof) of the run to this file")
+	memProfile := flag.String("memprofile", "", "Write a memory (heap) profile (pprof) to this file once the run completes")
+	resolveOrder := flag.String("resolve-order", "args-first", "Order of the hostnames when combining arguments and -input files: 'args-first' or 'files-first' (the files in the order given)")
+	inputFormat := flag.String("input-format", "text", "Format of the -input files: 'text' (one hostname per line), 'json' (an array of hostnames) or 'csv' (with a header row; see -input-column)")
+	inputColumn := flag.String("input-column", "hostname", "With -input-format csv, the name of the column holding the hostnames")
+	filterArg := flag.String("filter", "", "Only resolve the hostnames from the input files matching this regular expression, e.g. '\\.prod\\.example\\.com$'")
+	transformCmd := flag.String("transform-cmd", "", "Shell command each hostname is piped through (on its stdin) before it's queried, e.g. \"sed 's/$/.corp.example.com/'\"; the first line of its output is queried instead, a hostname whose command fails or outputs an invalid name is skipped with an error, and duplicates are dropped")
+	parallelFiles := flag.Bool("parallel-files", false, "Resolve each -input file as an independent batch, concurrently, with its own summary")
+	delay := flag.Duration("delay", 0, "Resolve the hostnames one at a time, waiting this long between them (e.g. '500ms'), for fragile servers; overrides -concurrency")
+	concurrency := flag.Int("concurrency", 0, "Maximum number of hostnames resolved at once across all batches (default: unlimited)")
+	failuresOnly := flag.Bool("failures-only", false, "Suppress successful resolution output and print only the failed hostnames at the end")
+	nagios := flag.Bool("nagios", false, "Act as a Nagios/Icinga plugin checking a single hostname: print only the status line (e.g. 'DNS OK - ... | time=12ms'), exiting 0, 1, 2 or 3 for OK, WARNING, CRITICAL or UNKNOWN (e.g. invalid arguments)")
+	warnLatency := flag.Duration("warn-latency", 0, "With -nagios, the state is WARNING when resolving takes this long or longer, e.g. '200ms'")
+	critLatency := flag.Duration("crit-latency", 0, "With -nagios, the state is CRITICAL when resolving takes this long or longer, e.g. '1s'")
+	countOnly := flag.Bool("count-only", false, "Suppress all per-hostname output and print just the number of hostnames resolved, for shell arithmetic")
+	failuresFormat := flag.String("failures-format", "lines", "Format for -failures-only: 'lines' (one hostname per line) or 'json'")
+	maxLatency := flag.Duration("max-latency", 0, "Log a warning for any hostname taking longer than this to resolve, e.g. '200ms'")
+	maxLatencyFatal := flag.Bool("max-latency-fatal", false, "Log exceeding -max-latency as an error and count it towards the failures for the run")
+	minTTL := flag.Duration("min-ttl", 0, "Log a warning for any answer record with a TTL below this, e.g. '60s' (queries the records' TTLs separately)")
+	showTTL := flag.Bool("show-ttl", false, "Log each hostname's effective TTL, the lowest of its answer records; behind a CNAME chain, along with each hop's own TTL (queries the records' TTLs separately)")
+	minTTLFatal := flag.Bool("min-ttl-fatal", false, "Log a TTL below -min-ttl as an error and count it towards the failures for the run")
+	checkPort := flag.Int("check-port", 0, "After resolving, attempt a TCP connect to each address on this port and report whether it's reachable")
+	checkPortTimeout := flag.Duration("check-port-timeout", 2*time.Second, "Timeout for each -check-port connect")
+	outputFormat := flag.String("output", "text", "Output format: 'text' (log lines), 'jsonl' (a line of JSON per hostname, written as each completes), 'csv', 'hosts' (/etc/hosts-style lines once the run completes) or 'dot' (a Graphviz graph of the hostnames, CNAMEs and addresses once the run completes)")
+	hostsAll := flag.Bool("hosts-all", false, "With -output hosts, write a line for each address rather than only each hostname's first")
+	hostsHeader := flag.Bool("hosts-header", false, "With -output hosts, start with a comment noting when the file was generated")
+	columnsArg := flag.String("columns", "", "Comma-separated columns for -compact and -output csv, in order: "+strings.Join(outputColumns, ","))
+	trailingDot := flag.String("trailing-dot", "strip", "Output names consistently without ('strip') or with ('keep') the trailing dot")
+	compact := flag.Bool("compact", false, "Condense each hostname's output into a single line once its forward and reverse lookups complete")
+	noRecurse := flag.Bool("no-recurse", false, "Query with Recursion Desired unset, logging referrals (authority NS records) rather than resolving recursively")
+	iterative := flag.Bool("iterative", false, "Resolve iteratively, as a recursive resolver would: from the root servers (or the -dnsserver addresses, e.g. a lab's root) down the referrals to the authoritative servers, logging each delegation")
+	qnameMinimization := flag.Bool("qname-minimization", false, "With -iterative, send each server only the labels needed to find the next zone cut (QNAME minimization, RFC 7816), for privacy")
+	diffDefault := flag.Bool("diff-default", false, "Resolve each hostname via -dnsserver and via the system's default resolver, reporting any differences")
+	recordTypeArg := flag.String("type", "addr", "The record type to look up: "+recordTypeNames()+" (see -list-types)")
+	tlsaPort := flag.Int("port", 443, "The service's port for -type tlsa, e.g. 25 for SMTP")
+	tlsaProto := flag.String("proto", "tcp", "The service's protocol for -type tlsa: tcp, udp or sctp")
+	classArg := flag.String("class", "in", "The query class for -type txt: in, ch (CHAOS, e.g. -class ch -type txt version.bind) or hs")
+	listTypes := flag.Bool("list-types", false, "List the record types supported by -type, then exit")
+	sourceIP := flag.String("source-ip", "", "Send the queries (UDP and TCP) from this local address, e.g. to choose the interface on a multi-homed host; requires -dnsserver")
+	tcpKeepalive := flag.Bool("tcp-keepalive", false, "Reuse TCP connections to the DNS servers between the lower-level client's queries over TCP (RFC 7766), i.e. -compare-transport's and the retries of truncated responses, rather than dialing one per query; the plain address lookups dial their own")
+	compareTransport := flag.Bool("compare-transport", false, "Query each hostname over both UDP and TCP, logging a warning when the answers differ (e.g. middlebox tampering or truncation bugs)")
+	cacheProbe := flag.Bool("cache-probe", false, "Query each hostname twice in quick succession, reporting the cold and warm latencies to analyze the server's caching")
+	udpRcvBuf := flag.Int("udp-rcvbuf", 0, "Set the UDP sockets' receive buffer to this many bytes (SO_RCVBUF), so responses aren't dropped in high-concurrency runs; 0 keeps the system's default")
+	bindDevice := flag.String("bind-device", "", "Send the queries via this network interface (SO_BINDTODEVICE; Linux only, may require CAP_NET_RAW)")
+	requireInterface := flag.String("require-interface", "", "Comma-separated network interfaces (e.g. a VPN's 'tun0') the queries must egress via; a query routed any other way isn't sent, failing closed (Linux only; requires -dnsserver)")
+	probeTimeout := flag.Int("probe-timeout", 1000, "Timeout in milliseconds for the startup probe of the -dnsserver addresses, failing the run at once when none responds; 0 skips the probe")
+	fallbackDefault := flag.Bool("fallback-default", false, "Fall back to the system's default resolver when none of the -dnsserver addresses responds to the startup probe, rather than failing")
+	use0x20 := flag.Bool("0x20", false, "Randomize the case of each query name (DNS 0x20) via the lower-level client, logging a warning when a response doesn't echo it exactly, a sign of spoofing or tampering")
+	maxResponseSize := flag.Int("max-response-size", 0, "Warn about responses larger than this many bytes (a potential amplification source or misconfiguration), querying via the lower-level client; each response's size is logged at debug level")
+	ecsArg := flag.String("ecs", "", "Send this client subnet with the queries (EDNS Client Subnet, e.g. '203.0.113.0/24') via the lower-level client, to see the geo-targeted answers for clients there")
+	txid := flag.Int("txid", -1, "TESTING ONLY: send queries with this fixed transaction ID (0-65535) via the lower-level client, e.g. to reproduce cache-poisoning scenarios in a lab")
+	mergeServers := flag.Bool("merge-servers", false, "Query every -dnsserver for each hostname and merge the unique addresses, logging which servers returned each")
+	answersSortedByRTT := flag.Bool("answers-sorted-by-rtt", false, "EXPERIMENTAL: with -merge-servers, order the merged addresses by the RTT of the queries returning them, fastest first, as a rough (heuristic) sense of which are closest")
+	axfr := flag.Bool("axfr", false, "Treat each hostname as a zone and perform a zone transfer (AXFR, over TCP) from the -dnsserver")
+	spfExpand := flag.Bool("spf-expand", false, "Treat each hostname as a domain, recursively expanding its SPF record and counting its DNS lookups (RFC 7208 limit of 10)")
+	outputDir := flag.String("output-dir", "", "Write each hostname's result as JSON to <dir>/<hostname>.json, creating the directory if needed")
+	audit := flag.Bool("audit", false, "After resolving, check each name for a CNAME coexisting with other records (e.g. a CNAME at the zone apex)")
+	suffixStatsArg := flag.Bool("report-unresolved-suffix-stats", false, "Once the run completes, log a table of the success and failure rates per registrable domain (e.g. example.co.uk), highest failure rate first, to spot the problematic zones")
+	selftest := flag.Bool("selftest", false, "Check the DNS server and the tool by resolving the root servers' hostnames, whose addresses are fixed, reporting PASS/FAIL against the known addresses; no other hostnames can be given")
+	dependsFile := flag.String("depends-file", "", "File of 'hostname depends-on other-hostname' lines; a hostname is only resolved once those it depends on have, and is skipped when one of them failed")
+	expectFile := flag.String("expect-file", "", "File of 'hostname expected_ip' lines; each hostname must resolve to its expected addresses (PASS/FAIL)")
+	infoPrefix := flag.String("info-prefix", defaultInfoPrefix, "Prefix for info log lines")
+	warnPrefix := flag.String("warn-prefix", defaultWarnPrefix, "Prefix for warning log lines")
+	errorPrefix := flag.String("error-prefix", defaultErrorPrefix, "Prefix for error log lines")
+	verbosity := flag.String("verbosity", "info", "Minimum level of the messages logged: 'debug', 'info', 'warn' or 'error' (e.g. quiet cron jobs)")
+	alwaysSummary := flag.Bool("always-summary", false, "Log the summary line even when -verbosity suppresses INFO messages")
+	noSummary := flag.Bool("no-summary", false, "Don't log the final summary line (nor those of -parallel-files or -interval), e.g. for clean single-host output in scripts; the per-hostname lines are still logged")
+	noPrefix := flag.Bool("no-prefix", false, "Log without the level prefixes (INFO: etc.), overriding -info-prefix, -warn-prefix and -error-prefix")
+	textfile := flag.String("textfile", "", "Write Prometheus text-format metrics (per-hostname success and duration) to this path after the run, e.g. a .prom file for node_exporter")
+	reportFile := flag.String("report-file", "", "Write a JSON report of the run (per-hostname status, counts, servers, duration) to this path")
+	warm := flag.Bool("warm", false, "Cache-warming mode; report only the number of hostnames warmed and any failures")
+	warmStatePath := flag.String("warm-state", "", "File storing last-warm timestamps; hostnames warmed within -warm-window are skipped")
+	watchStatePath := flag.String("watch-state", "", "File storing each hostname's addresses; after resolving, log which are NEW, CHANGED, UNCHANGED or REMOVED since the last run, then update it")
+	warmWindow := flag.Duration("warm-window", 5*time.Minute, "Skip hostnames warmed within this duration (used with -warm-state)")
 	flag.Parse()
+	// before any validation, so a usage error is a plugin state too
+	if *nagios {
+		nagiosOut = &nagiosWriter{w: os.Stdout, warn: *warnLatency, crit: *critLatency}
+	}
+
+	if *noPrefix {
+		SetLogPrefixes("", "", "", "")
+	} else {
+		SetLogPrefixes(defaultDebugPrefix, *infoPrefix, *warnPrefix, *errorPrefix)
+	}
+	if !SetLogVerbosity(*verbosity) {
+		LogError("Invalid value provided for verbosity: '%s'\n", *verbosity)
+		fatalUsage()
+	}
+	SetAlwaysSummary(*alwaysSummary)
+	if *noSummary && *alwaysSummary {
+		LogError("-no-summary can't be combined with -always-summary\n")
+		fatalUsage()
+	}
 
 	if *timeoutArg < 0 {
 		LogError("Invalid value provided for timeout: '%d'\n", *timeoutArg)
-		log.Fatalf(helpMsg)
+		fatalUsage()
+	}
+
+	var deadline time.Time
+	if *deadlineArg != "" {
+		timeoutSet := false
+		flag.Visit(func(f *flag.Flag) {
+			timeoutSet = timeoutSet || f.Name == "timeout"
+		})
+		if timeoutSet {
+			LogError("-deadline and -timeout are mutually exclusive\n")
+			fatalUsage()
+		}
+
+		var err error
+		deadline, err = time.Parse(time.RFC3339, *deadlineArg)
+		if err != nil {
+			LogError("Invalid value provided for deadline: '%s' (expected RFC 3339, e.g. 2026-01-02T12:00:00Z)\n", *deadlineArg)
+			fatalUsage()
+		}
+		if !deadline.After(time.Now()) {
+			LogError("Deadline '%s' is not in the future\n", *deadlineArg)
+			fatalUsage()
+		}
+	}
+
+	if *interval < 0 || (*interval > 0 && !deadline.IsZero()) {
+		LogError("Invalid value provided for interval: '%s' (must be positive, and can't be combined with -deadline)\n", *interval)
+		fatalUsage()
+	}
+
+	if *concurrency < 0 {
+		LogError("Invalid value provided for concurrency: '%d'\n", *concurrency)
+		fatalUsage()
+	}
+
+	if *udpRcvBuf < 0 {
+		LogError("Invalid value provided for udp rcvbuf: '%d'\n", *udpRcvBuf)
+		fatalUsage()
+	}
+
+	if *probeTimeout < 0 {
+		LogError("Invalid value provided for probe timeout: '%d'\n", *probeTimeout)
+		fatalUsage()
+	}
+	if *fallbackDefault && (*dnsServerIp == "" || *probeTimeout == 0) {
+		LogError("-fallback-default requires -dnsserver and a -probe-timeout\n")
+		fatalUsage()
+	}
+
+	if *answerLimit < 0 {
+		LogError("Invalid value provided for answer limit: '%d'\n", *answerLimit)
+		fatalUsage()
+	}
+
+	if *fcrdns && *firstIP {
+		LogError("-fcrdns can't be combined with -first-ip, which skips the reverse lookups\n")
+		fatalUsage()
+	}
+
+	if *maxResponseSize < 0 {
+		LogError("Invalid value provided for max response size: '%d'\n", *maxResponseSize)
+		fatalUsage()
+	}
+
+	var ecs *net.IPNet
+	if *ecsArg != "" {
+		var err error
+		if ecs, err = parseECS(*ecsArg); err != nil {
+			LogError("Invalid value provided for ecs: '%s' (e.g. 203.0.113.0/24)\n", *ecsArg)
+			fatalUsage()
+		}
+	}
+
+	if *txid < -1 || *txid > 0xffff {
+		LogError("Invalid value provided for txid: '%d' (0-65535)\n", *txid)
+		fatalUsage()
+	}
+
+	if *delay < 0 {
+		LogError("Invalid value provided for delay: '%s'\n", *delay)
+		fatalUsage()
+	}
+
+	if *maxFailures < 0 {
+		LogError("Invalid value provided for max failures: '%d'\n", *maxFailures)
+		fatalUsage()
+	}
+
+	if *timeoutEscalation < 0 {
+		LogError("Invalid value provided for timeout escalation: '%s'\n", *timeoutEscalation)
+		fatalUsage()
+	}
+	if *timeoutEscalation > 0 && *retries == 0 {
+		LogError("-timeout-escalation requires -retries\n")
+		fatalUsage()
+	}
+
+	if *retries < 0 || *retryBackoff < 0 {
+		LogError("Invalid value provided for retries: '%d' (backoff '%s')\n", *retries, *retryBackoff)
+		fatalUsage()
+	}
+
+	if *checkPort < 0 || *checkPort > 65535 || *checkPortTimeout <= 0 {
+		LogError("Invalid value provided for check port: '%d' (timeout '%s')\n", *checkPort, *checkPortTimeout)
+		fatalUsage()
+	}
+
+	if *failuresFormat != "lines" && *failuresFormat != "json" {
+		LogError("Invalid value provided for failures format: '%s'\n", *failuresFormat)
+		fatalUsage()
+	}
+
+	if *sourceIP != "" && *dnsServerIp == "" {
+		LogError("-source-ip requires -dnsserver\n")
+		fatalUsage()
+	}
+	if *qnameMinimization && !*iterative {
+		LogError("-qname-minimization requires -iterative\n")
+		fatalUsage()
+	}
+	if *answersSortedByRTT && (!*mergeServers || *stable) {
+		LogError("-answers-sorted-by-rtt requires -merge-servers, and can't be combined with -stable, which sorts the addresses\n")
+		fatalUsage()
+	}
+	// the system's resolver may not dial through the check
+	if *requireInterface != "" && (*dnsServerIp == "" || *fallbackDefault || *diffDefault) {
+		LogError("-require-interface requires -dnsserver, and can't be combined with -fallback-default or -diff-default\n")
+		fatalUsage()
+	}
+
+	if *axfr && *dnsServerIp == "" {
+		LogError("-axfr requires the zone's authoritative server via -dnsserver\n")
+		fatalUsage()
+	}
+
+	if *outputFormat != "text" && *outputFormat != "jsonl" && *outputFormat != "csv" && *outputFormat != "hosts" && *outputFormat != "dot" {
+		LogError("Invalid value provided for output: '%s'\n", *outputFormat)
+		fatalUsage()
+	}
+
+	if *stable && *outputFormat == "text" && !*compact && !*failuresOnly {
+		LogError("-stable requires -compact, -failures-only or an -output other than text\n")
+		fatalUsage()
+	}
+	if !SetDurationFormat(*durationFormat) {
+		LogError("Invalid value provided for duration format: '%s' (ms or human)\n", *durationFormat)
+		fatalUsage()
+	}
+
+	resultSortKey, ok := resultSortKeys[*sortKey]
+	if !ok {
+		LogError("Invalid value provided for sortkey: '%s' (%s)\n", *sortKey, resultSortKeyNames())
+		fatalUsage()
+	}
+	if *sortKey != "hostname" && !*stable {
+		LogError("-sortkey requires -stable\n")
+		fatalUsage()
+	}
+
+	if *outputFormat != "text" && (*compact || *failuresOnly) {
+		LogError("-output %s can't be combined with -compact or -failures-only\n", *outputFormat)
+		fatalUsage()
+	}
+	if *countOnly && (*outputFormat != "text" || *compact || *failuresOnly) {
+		LogError("-count-only can't be combined with -output, -compact or -failures-only\n")
+		fatalUsage()
+	}
+
+	if *nagios && (*outputFormat != "text" || *compact || *failuresOnly || *countOnly || *interval > 0 || *warm) {
+		LogError("-nagios can't be combined with -output, -compact, -failures-only, -count-only, -interval or -warm\n")
+		fatalUsage()
+	}
+	// the per-hostname lines are suppressed (see `r.quiet`), but the run's
+	// other info lines mustn't mix with the output either
+	if *countOnly || *failuresOnly || *nagios || *outputFormat != "text" {
+		SetInfoToStderr()
+	}
+	if (*warnLatency != 0 || *critLatency != 0) && !*nagios {
+		LogError("-warn-latency and -crit-latency require -nagios\n")
+		fatalUsage()
+	}
+	if *warnLatency < 0 || *critLatency < 0 || (*warnLatency > 0 && *critLatency > 0 && *warnLatency > *critLatency) {
+		LogError("Invalid values provided for warn latency and crit latency: '%s' and '%s' (must be positive, with the warning at most the critical)\n", *warnLatency, *critLatency)
+		fatalUsage()
+	}
+
+	if (*outputFormat == "hosts" || *outputFormat == "dot") && *interval > 0 {
+		LogError("-output %s can't be combined with -interval\n", *outputFormat)
+		fatalUsage()
+	}
+
+	var columns []string
+	if *columnsArg != "" || *outputFormat == "csv" {
+		if *columnsArg == "" {
+			*columnsArg = defaultCSVColumns
+		}
+		var err error
+		if columns, err = parseColumns(*columnsArg); err != nil {
+			LogError("Invalid value provided for columns: %s\n", err.Error())
+			fatalUsage()
+		}
+		if !*compact && *outputFormat != "csv" {
+			LogError("-columns requires -compact or -output csv\n")
+			fatalUsage()
+		}
+	}
+
+	if *listTypes {
+		listRecordTypes(os.Stdout)
+		exit(0)
+	}
+
+	recordType, err := lookupRecordType(*recordTypeArg)
+	if err != nil {
+		LogError("Invalid value provided for type: %s\n", err.Error())
+		fatalUsage()
+	}
+
+	class, ok := queryClasses[strings.ToLower(*classArg)]
+	if !ok {
+		LogError("Invalid value provided for class: '%s' (in, ch or hs)\n", *classArg)
+		fatalUsage()
+	}
+	if class != queryClasses["in"] && recordType.name != "txt" && recordType.name != "all" {
+		LogError("-class %s requires -type txt (or all)\n", *classArg)
+		fatalUsage()
+	}
+
+	if *tlsaPort < 1 || *tlsaPort > 65535 {
+		LogError("Invalid value provided for port: '%d' (1-65535)\n", *tlsaPort)
+		fatalUsage()
+	}
+	if *tlsaProto != "tcp" && *tlsaProto != "udp" && *tlsaProto != "sctp" {
+		LogError("Invalid value provided for proto: '%s' (tcp, udp or sctp)\n", *tlsaProto)
+		fatalUsage()
+	}
+	if (*tlsaPort != 443 || *tlsaProto != "tcp") && recordType.name != "tlsa" && recordType.name != "all" {
+		LogError("-port and -proto require -type tlsa (or all)\n")
+		fatalUsage()
+	}
+	// only the plain lookups are cached
+	if *cacheFile != "" && (recordType.name != "addr" || *axfr || *spfExpand || *noRecurse || *iterative || *diffDefault || *mergeServers || *cacheProbe || *compareTransport || *warm) {
+		LogError("-cache-file can't be combined with -type, -axfr, -spf-expand, -no-recurse, -iterative, -diff-default, -merge-servers, -cache-probe, -compare-transport or -warm\n")
+		fatalUsage()
+	}
+	// these check the lookup itself, which a cached hostname doesn't get
+	if *cacheFile != "" && (*maxLatency > 0 || *minTTL > 0 || *showTTL) {
+		LogError("-cache-file can't be combined with -max-latency, -min-ttl or -show-ttl\n")
+		fatalUsage()
+	}
+
+	if *trailingDot != "strip" && *trailingDot != "keep" {
+		LogError("Invalid value provided for trailing dot: '%s'\n", *trailingDot)
+		fatalUsage()
+	}
+
+	if *diffDefault && *dnsServerIp == "" {
+		LogError("-diff-default requires -dnsserver\n")
+		fatalUsage()
+	}
+
+	if *reverseFamily != "both" && *reverseFamily != string(IPv4) && *reverseFamily != string(IPv6) {
+		LogError("Invalid value provided for reverse family: '%s'\n", *reverseFamily)
+		fatalUsage()
 	}
 
 	if !validNetworkString(*networkType) {
 		LogError("Invalid value provided for network string: '%s'\n", *networkType)
-		log.Fatalf(helpMsg)
+		fatalUsage()
+	}
+
+	if *resolveOrder != "args-first" && *resolveOrder != "files-first" {
+		LogError("Invalid value provided for resolve order: '%s' (args-first or files-first)\n", *resolveOrder)
+		fatalUsage()
+	}
+
+	if _, ok := inputFormats[*inputFormat]; !ok {
+		LogError("Invalid value provided for input format: '%s' (text, json or csv)\n", *inputFormat)
+		fatalUsage()
+	}
+
+	var filter *regexp.Regexp
+	if *filterArg != "" {
+		filter, err = regexp.Compile(*filterArg)
+		if err != nil {
+			LogError("Invalid value provided for filter: '%s': %s\n", *filterArg, err.Error())
+			fatalUsage()
+		}
+	}
+	// the input files' hostnames, with the filter applied
+	filterTotal, filterMatched := 0, 0
+	readInput := func(path string) []string {
+		names := mustReadHostnamesFile(path, *inputFormat, *inputColumn)
+		if filter == nil {
+			return names
+		}
+		filterTotal += len(names)
+		names = filterHostnames(names, filter)
+		filterMatched += len(names)
+		return names
 	}
 
-	// only hostnames are required
 	hostnames := flag.Args()
-	if len(hostnames) == 0 {
-		log.Fatalf(helpMsg)
+	if *reverseCIDR != "" {
+		// each address in the blocks is queried via its reverse name
+		for _, cidr := range strings.Split(*reverseCIDR, ",") {
+			names, err := reverseNamesForCIDR(cidr)
+			if err != nil {
+				LogError("Invalid value provided for reverse cidr: %s\n", err.Error())
+				fatalUsage()
+			}
+			hostnames = append(hostnames, names...)
+		}
+	}
+	var batches []hostnameBatch
+	if *parallelFiles {
+		// each input file is an independent batch with its own summary
+		for _, path := range inputFiles {
+			batches = append(batches, hostnameBatch{label: path, hostnames: readInput(path)})
+		}
+		if len(hostnames) > 0 {
+			args := hostnameBatch{label: "arguments", hostnames: hostnames}
+			if *resolveOrder == "args-first" {
+				batches = append([]hostnameBatch{args}, batches...)
+			} else {
+				batches = append(batches, args)
+			}
+		}
+	} else {
+		var fileHostnames []string
+		for _, path := range inputFiles {
+			fileHostnames = append(fileHostnames, readInput(path)...)
+		}
+		if *resolveOrder == "args-first" {
+			hostnames = append(hostnames, fileHostnames...)
+		} else {
+			hostnames = append(fileHostnames, hostnames...)
+		}
+		batches = append(batches, hostnameBatch{hostnames: hostnames})
+	}
+	// the names in the depends and expect files are taken as they are, so
+	// must be the transformed ones
+	transformSkipped := 0
+	if *transformCmd != "" {
+		remaining := 0
+		for i := range batches {
+			var skipped int
+			batches[i].hostnames, skipped = transformHostnames(context.Background(), *transformCmd, batches[i].hostnames, *allowWildcard)
+			transformSkipped += skipped
+			remaining += len(batches[i].hostnames)
+		}
+		if remaining == 0 && transformSkipped > 0 {
+			LogError("No hostnames left to resolve, as -transform-cmd failed for all %d\n", transformSkipped)
+			exit(exitAllFailed)
+		}
+		if !*parallelFiles {
+			hostnames = batches[0].hostnames
+		}
+	}
+
+	// the hostnames with dependencies are resolved along with the others,
+	// each one's dependencies ahead of it
+	var deps *dependencies
+	if *dependsFile != "" {
+		if *parallelFiles {
+			LogError("-depends-file can't be combined with -parallel-files\n")
+			fatalUsage()
+		}
+		var err error
+		deps, err = loadDependencies(*dependsFile)
+		if err != nil {
+			LogError("Failed to read depends file '%s': %s\n", *dependsFile, err.Error())
+			exit(1)
+		}
+		listed := map[string]bool{}
+		for _, hostname := range hostnames {
+			listed[expectKey(hostname)] = true
+		}
+		for _, hostname := range deps.hostnames {
+			if !listed[expectKey(hostname)] {
+				hostnames = append(hostnames, hostname)
+			}
+		}
+		hostnames = deps.order(hostnames)
+		batches = []hostnameBatch{{hostnames: hostnames}}
+	}
+
+	// the expected hostnames are resolved when no others are provided
+	var expected *expectations
+	if *selftest {
+		if len(hostnames) > 0 || len(inputFiles) > 0 || *expectFile != "" || *dependsFile != "" {
+			LogError("-selftest can't be combined with other hostnames, -input, -expect-file or -depends-file\n")
+			fatalUsage()
+		}
+		expected = selftestExpectations(NetworkString(*networkType))
+		hostnames = expected.hostnames
+		batches = []hostnameBatch{{hostnames: hostnames}}
+	} else if *expectFile != "" {
+		var err error
+		expected, err = loadExpectFile(*expectFile)
+		if err != nil {
+			LogError("Failed to read expect file '%s': %s\n", *expectFile, err.Error())
+			exit(1)
+		}
+		if len(hostnames) == 0 && len(inputFiles) == 0 {
+			hostnames = expected.hostnames
+			batches = []hostnameBatch{{hostnames: hostnames}}
+		}
+	}
+
+	// only hostnames are required
+	if len(hostnames) == 0 && len(inputFiles) == 0 {
+		fatalUsage()
 	}
 
 	r, err := getDnsResolver(dnsServerIp)
 	if err != nil {
-		LogError(err.Error())
-		os.Exit(1)
+		LogError("%s", err.Error())
+		exit(1)
+	}
+
+	// skip the hostnames warmed recently
+	var state warmState
+	var skipped []string
+	if *warmStatePath != "" {
+		state, err = loadWarmState(*warmStatePath)
+		if err != nil {
+			LogError("Failed to read warm state file '%s': %s\n", *warmStatePath, err.Error())
+			exit(1)
+		}
+		for i := range batches {
+			var batchSkipped []string
+			batches[i].hostnames, batchSkipped = state.pending(batches[i].hostnames, *warmWindow, time.Now())
+			skipped = append(skipped, batchSkipped...)
+		}
+	}
+	hostnames = nil
+	for _, batch := range batches {
+		hostnames = append(hostnames, batch.hostnames...)
+	}
+	if *nagios && len(hostnames) != 1 {
+		LogError("-nagios checks a single hostname, but %d were provided\n", len(hostnames))
+		fatalUsage()
+	}
+
+	// compare each run's addresses with the previous run's
+	var watched watchState
+	if *watchStatePath != "" {
+		watched, err = loadWatchState(*watchStatePath)
+		if err != nil {
+			LogError("Failed to read watch state file '%s': %s\n", *watchStatePath, err.Error())
+			exit(1)
+		}
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	if *shuffleServers {
+		r.ShuffleServers(*seed)
+	}
+	r.SetRetryBackoff(*retryBackoff, *seed)
+	r.SetConcurrency(*concurrency)
+	r.SetDelay(*delay)
+	// before the dial controls, as -require-interface checks the route from it
+	if *sourceIP != "" {
+		ip := net.ParseIP(*sourceIP)
+		if ip == nil {
+			LogError("Invalid value provided for source ip: '%s'\n", *sourceIP)
+			fatalUsage()
+		}
+		if err := r.SetSourceIP(ip); err != nil {
+			LogError("%s\n", err.Error())
+			exit(1)
+		}
+	}
+	var dialControls []func(network, address string, c syscall.RawConn) error
+	if *bindDevice != "" {
+		if _, err := net.InterfaceByName(*bindDevice); err != nil {
+			LogError("Invalid value provided for bind device: '%s': %s\n", *bindDevice, err.Error())
+			fatalUsage()
+		}
+		if control, err := bindDeviceControl(*bindDevice); err != nil {
+			LogWarning("Ignoring -bind-device: %s\n", err.Error())
+		} else {
+			dialControls = append(dialControls, control)
+		}
+	}
+	if *udpRcvBuf > 0 {
+		dialControls = append(dialControls, udpRcvBufControl(*udpRcvBuf))
+	}
+	// after -bind-device, so a socket bound to a device is checked against it
+	if *requireInterface != "" {
+		control, err := requireInterfaceControl(strings.FieldsFunc(*requireInterface, func(c rune) bool { return c == ',' || c == ' ' }), r.sourceIP)
+		if err != nil {
+			LogError("-require-interface: %s\n", err.Error())
+			exit(1)
+		}
+		dialControls = append(dialControls, control)
+	}
+	r.SetDialControl(chainDialControls(dialControls...))
+	r.CheckDialers()
+	// rather than every hostname failing in turn against a server that's down
+	if *probeTimeout > 0 && *dnsServerIp != "" {
+		if err := r.ProbeServers(context.Background(), time.Duration(*probeTimeout)*time.Millisecond); err != nil {
+			if !*fallbackDefault {
+				LogError("%s\n", err.Error())
+				exit(exitUnreachable)
+			}
+			LogWarning("%s; falling back to the system's default resolver\n", err.Error())
+			r.FallBackToDefault()
+		}
+	}
+	r.quiet = *warm || *failuresOnly || *countOnly || *compact || *nagios || *outputFormat != "text"
+	if filter != nil {
+		// only for the human output, like the per-hostname lines
+		r.logInfo("Filter '%s' matched %d of %d hostnames from the input files\n", *filterArg, filterMatched, filterTotal)
+	}
+	r.queryTTL = hasColumn(columns, "ttl")
+	r.queryCNAME = *outputFormat == "dot"
+	var out OutputWriter
+	switch {
+	case *outputFormat == "jsonl":
+		out = newJSONLWriter(os.Stdout, r)
+	case *outputFormat == "csv":
+		out = newCSVWriter(os.Stdout, r, columns)
+	case *outputFormat == "hosts":
+		out = &hostsWriter{w: os.Stdout, allAddrs: *hostsAll, header: *hostsHeader}
+	case *outputFormat == "dot":
+		out = &dotWriter{w: os.Stdout}
+	case *nagios:
+		out = nagiosOut
+	case *countOnly:
+		out = &countWriter{w: os.Stdout}
+	case *failuresOnly:
+		out = &failuresWriter{w: os.Stdout, failed: r.Failed, json: *failuresFormat == "json"}
+	default:
+		out = &textWriter{r: r, compact: *compact && !*warm, columns: columns, noSummary: *noSummary}
+	}
+	// -stable holds back the per-result output until the run completes
+	if !*stable {
+		r.OnResult(out.WriteResult)
+	}
+	r.reverseErrorsFatal = *reverseErrorsFatal
+	r.explain = *explain
+	r.explainServers()
+	if *cacheReverse {
+		r.reverseCache = newReverseCache()
+	}
+	if *cacheFile != "" {
+		r.resultCache, err = loadResultCache(*cacheFile)
+		if errors.Is(err, errCorruptCache) {
+			LogWarning("Discarding the cache file '%s': %s\n", *cacheFile, err.Error())
+		} else if err != nil {
+			LogError("Failed to read cache file '%s': %s\n", *cacheFile, err.Error())
+			exit(1)
+		}
+		// the entries expire with the answers' TTLs
+		r.queryTTL = true
+	}
+	r.rawIPv6 = *rawIPv6
+	r.appendDomain = *appendDomainArg
+	r.fallbackSuffix = strings.Trim(*fallbackSuffix, ".")
+	r.allowWildcard = *allowWildcard
+	r.retries = *retries
+	r.attemptTimeout = *timeoutEscalation
+	r.retryOnEmpty = *retryOnEmpty
+	r.partialOK = *partialOK
+	r.firstIP = *firstIP
+	r.dependencies = deps
+	r.answerLimit = *answerLimit
+	r.sortIPs = *sortIPsArg
+	r.reverseIgnore = parseSuffixList(*reverseIgnoreSuffix)
+	r.idnStrict = *idnStrict
+	r.idnAllow = parseSuffixList(*idnAllow)
+	r.noRecurse = *noRecurse
+	r.iterative = *iterative
+	r.qnameMinimization = *qnameMinimization
+	r.maxLatency = *maxLatency
+	r.maxLatencyFatal = *maxLatencyFatal
+	r.minTTL = *minTTL
+	r.minTTLFatal = *minTTLFatal
+	r.showTTL = *showTTL
+	r.checkPortNum = *checkPort
+	r.checkPortTimeout = *checkPortTimeout
+	r.fcrdns = *fcrdns
+	r.diffDefault = *diffDefault
+	r.mergeServers = *mergeServers
+	r.sortByRTT = *answersSortedByRTT
+	r.cacheProbe = *cacheProbe
+	r.compareTransport = *compareTransport
+	if *tcpKeepalive {
+		r.tcpPool = newTCPPool()
+	}
+	if *txid >= 0 {
+		id := uint16(*txid)
+		r.txid = &id
+	}
+	r.ecs = ecs
+	r.maxResponseSize = *maxResponseSize
+	r.use0x20 = *use0x20
+	r.recordType = recordType
+	r.class = class
+	r.tlsaPort = *tlsaPort
+	r.tlsaProto = *tlsaProto
+	r.axfr = *axfr
+	r.trailingDot = *trailingDot
+	r.spfExpand = *spfExpand
+	r.audit = *audit
+	if *reverseFamily != "both" {
+		r.reverseFamily = NetworkString(*reverseFamily)
 	}
 
+	if *outputDir != "" {
+		if err := os.MkdirAll(*outputDir, 0755); err != nil {
+			LogError("Failed to create output directory '%s': %s\n", *outputDir, err.Error())
+			exit(1)
+		}
+		// a failed write only loses that hostname's file
+		r.OnResult(func(result *ResolveResult) {
+			if err := writeResultFile(*outputDir, result, r); err != nil {
+				LogError("Failed to write result for %s to '%s': %s\n", result.Hostname, *outputDir, err.Error())
+			}
+		})
+	}
+
+	// the spans still queued are flushed once the run completes
+	shutdownTracing := func() {}
+	if *otelEndpoint != "" {
+		shutdown, err := r.EnableTracing(context.Background(), *otelEndpoint)
+		if err != nil {
+			LogError("Invalid value provided for otel endpoint: '%s': %s\n", *otelEndpoint, err.Error())
+			fatalUsage()
+		}
+		shutdownTracing = func() {
+			ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				LogError("Failed to export traces to '%s': %s\n", *otelEndpoint, err.Error())
+			}
+		}
+	}
+
+	// stopped once the run completes, so the profiles cover an interrupted run too
+	stopProfiles := startProfiles(*cpuProfile, *memProfile)
+
+	// an interrupt cancels the outstanding lookups, so the run (and its report) completes with partial results
+	rootCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	timeout := time.Duration(*timeoutArg) * time.Millisecond
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	if !deadline.IsZero() {
+		timeout = time.Until(deadline)
+	}
+	// each run (or -interval cycle) is bounded by its own context, derived from the root
+	var breaker *failureBreaker
+	if *maxFailures > 0 {
+		breaker = newFailureBreaker(*maxFailures, r.Failed)
+		r.OnResult(breaker.record)
+	}
+	runContext := func() (context.Context, context.CancelFunc) {
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if !deadline.IsZero() {
+			ctx, cancel = context.WithDeadline(rootCtx, deadline)
+		} else {
+			ctx, cancel = context.WithTimeout(rootCtx, timeout)
+		}
+		if breaker != nil {
+			ctx = breaker.start(ctx)
+		}
+		return ctx, cancel
+	}
+	// the run's remaining lookups were canceled at -max-failures
+	checkBreaker := func() {
+		if breaker != nil && breaker.tripped() {
+			LogError("Aborted the run after %d hostnames failed (-max-failures)\n", *maxFailures)
+		}
+	}
 
-	r.ResolveHostnames(ctx, NetworkString(*networkType), hostnames)
+	// with -stable, the output is written once all of a run's results are in
+	writeStable := func(results []*ResolveResult) []*ResolveResult {
+		if !*stable {
+			return results
+		}
+		results = stableResults(results, resultSortKey)
+		for _, result := range results {
+			out.WriteResult(result)
+		}
+		return results
+	}
 
-	totalDuration := time.Since(totalStart)
-	addrs := strings.Join(hostnames, ", ")
+	watchChanges := func(results []*ResolveResult) {
+		if watched == nil {
+			return
+		}
+		counts := watched.compare(r, results)
+		LogSummary("Changes since the last run: %d changed, %d new, %d removed, %d unchanged\n", counts.changed, counts.new, counts.removed, counts.unchanged)
+		if err := watched.save(*watchStatePath); err != nil {
+			LogError("Failed to write watch state file '%s': %s\n", *watchStatePath, err.Error())
+		}
+	}
 
-	var addrStr string
-	if len(hostnames) > 1 {
-		addrStr = "addresses"
+	var results []*ResolveResult
+	var reason string
+	if *interval > 0 {
+		results = runCycles(rootCtx, *interval, func(n int) []*ResolveResult {
+			ctx, cancel := runContext()
+			defer cancel()
+			start := time.Now()
+			results := writeStable(resolveBatches(ctx, r, NetworkString(*networkType), batches, !*noSummary))
+			out.WriteSummary(&Summary{Label: fmt.Sprintf("cycle %d", n), Hostnames: hostnames, Results: results, Duration: time.Since(start), Reason: cancelReason(ctx)})
+			checkBreaker()
+			watchChanges(results)
+			// rather than keeping them open until the next cycle
+			r.CloseIdleConnections()
+			return results
+		})
 	} else {
-		addrStr = "address"
+		ctx, cancel := runContext()
+		results = writeStable(resolveBatches(ctx, r, NetworkString(*networkType), batches, !*noSummary))
+		reason = cancelReason(ctx)
+		cancel()
+		checkBreaker()
+		watchChanges(results)
+		r.CloseIdleConnections()
 	}
+	resolved := countResolved(results)
 
-	LogInfo("%s for %d %s (%s): %d ms\n", prefixStr(totalDuration, timeout), len(hostnames), addrStr, addrs, totalDuration.Milliseconds())
+	if *warm {
+		LogSummary("Warmed %d of %d hostnames (%d skipped as recently warmed)\n", resolved, len(hostnames), len(skipped))
+	}
+
+	if r.resultCache != nil {
+		// only for the human output, like the per-hostname lines
+		r.logInfo("Served %d of %d hostnames from the cache file\n", r.resultCache.hitCount(), len(hostnames))
+		if err := r.resultCache.save(*cacheFile, time.Now()); err != nil {
+			LogError("Failed to write cache file '%s': %s\n", *cacheFile, err.Error())
+		}
+	}
+
+	if state != nil {
+		state.update(results, time.Now())
+		if err := state.save(*warmStatePath); err != nil {
+			LogError("Failed to write warm state file '%s': %s\n", *warmStatePath, err.Error())
+		}
+	}
+
+	totalDuration := time.Since(totalStart)
+	if *interval == 0 {
+		// with -interval, each cycle has been summarized
+		out.WriteSummary(&Summary{Hostnames: hostnames, Results: results, Duration: totalDuration, Reason: reason})
+	}
+
+	if *suffixStatsArg {
+		logSuffixStats(results)
+	}
+
+	if *reportFile != "" {
+		report := newRunReport(results, r, totalDuration)
+		report.Interrupted = rootCtx.Err() != nil
+		if err := report.write(*reportFile); err != nil {
+			LogError("Failed to write report file '%s': %s\n", *reportFile, err.Error())
+		}
+	}
+
+	if *textfile != "" {
+		if err := writeTextfile(*textfile, results, r, totalDuration); err != nil {
+			LogError("Failed to write textfile '%s': %s\n", *textfile, err.Error())
+		}
+	}
+
+	stopProfiles()
+	shutdownTracing()
+
+	expectFailed := 0
+	if expected != nil {
+		expectFailed = expected.check(results)
+	}
+
+	// in `-nagios` mode the plugin's state is the exit status, even when OK
+	if code := exitCode(r, results, *servFailFatal, expectFailed+transformSkipped); code != 0 || nagiosOut != nil {
+		exit(code)
+	}
+}
+
+// the run's exit status given its `results`; `otherFailures` are failures
+// counted apart from the results (unmet expectations, skipped hostnames)
+func exitCode(r *Resolver, results []*ResolveResult, servFailFatal bool, otherFailures int) int {
+	// a broken server is reported apart from bad names, when asked to
+	if servFailFatal && countServFails(results) > 0 {
+		return exitServFail
+	}
+
+	// distinguish a total failure from a partial one for automated callers
+	if len(results) > 0 && countResolved(results) == 0 {
+		return exitAllFailed
+	}
+	if otherFailures > 0 {
+		return exitFailure
+	}
+	for _, result := range results {
+		if r.Failed(result) {
+			return exitFailure
+		}
+	}
+	return 0
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllFailed(t *testing.T) {
+	ts := newTestServer(t)
+	r := newTestResolver(t, ts, nil)
+	stdout, _ := captureLogs(t)
+
+	hostnames := []string{"nxdomain.test", "servfail.test", "missing.test"}
+	results := r.ResolveHostnames(testContext(t), "ip", hostnames)
+	logSummary("", hostnames, results, time.Second, "")
+
+	if got := stdout.String(); !strings.Contains(got, "; 0 of 3 resolved; 1 SERVFAIL\n") {
+		t.Errorf("summary %q doesn't report that nothing resolved", got)
+	}
+	if code := exitCode(r, results, false, 0); code != exitAllFailed {
+		t.Errorf("got exit code %d, want %d", code, exitAllFailed)
+	}
+	// a SERVFAIL is reported apart when asked to
+	if code := exitCode(r, results, true, 0); code != exitServFail {
+		t.Errorf("got exit code %d with -servfail-fatal, want %d", code, exitServFail)
+	}
+}
+
+func TestPartiallyFailed(t *testing.T) {
+	ts := newTestServer(t)
+	r := newTestResolver(t, ts, nil)
+	stdout, _ := captureLogs(t)
+
+	hostnames := []string{"ok.test", "nxdomain.test"}
+	results := r.ResolveHostnames(testContext(t), "ip", hostnames)
+	logSummary("", hostnames, results, time.Second, "")
+
+	if got := stdout.String(); !strings.Contains(got, "; 1 of 2 resolved\n") {
+		t.Errorf("summary %q doesn't report the hostnames resolved", got)
+	}
+	if code := exitCode(r, results, false, 0); code != exitFailure {
+		t.Errorf("got exit code %d, want %d", code, exitFailure)
+	}
+	if code := exitCode(r, results[:1], false, 0); code != 0 {
+		t.Errorf("got exit code %d for a successful run, want 0", code)
+	}
+}
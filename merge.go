@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolve `hostname` via every configured server at once, logging the
+// deduplicated union of the addresses and which servers returned each; fails
+// only when no server answered. With `-answers-sorted-by-rtt`, the queries go
+// via the lower-level client and the addresses are ordered by the lowest RTT
+// of the queries returning them
+func (r *Resolver) ResolveMergeServers(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	ipsByServer := make([][]net.IP, len(r.servers))
+	rttsByServer := make([][]time.Duration, len(r.servers))
+	errs := make([]error, len(r.servers))
+	var wg sync.WaitGroup
+	for i, ns := range r.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r.sortByRTT {
+				ipsByServer[i], rttsByServer[i], errs[i] = r.rawLookupIPTimed(ctx, ns, network, hostname)
+			} else {
+				ipsByServer[i], errs[i] = ns.resolver.LookupIP(ctx, string(network), hostname)
+			}
+		}()
+	}
+	wg.Wait()
+	result.Duration = time.Since(startTime)
+
+	// addresses keyed by their normalized string, with the servers returning each
+	ips := map[string]net.IP{}
+	var seen []string // the addresses in the order first returned
+	sources := map[string][]string{}
+	// the lowest RTT of the queries returning each address, when known
+	rtts := map[string]time.Duration{}
+	var contributors []string
+	var firstErr error
+	for i, ns := range r.servers {
+		if errs[i] != nil {
+			r.logWarning("Query via %s for %s failed: '%s'\n", ns, hostname, shortError(errs[i]))
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		contributors = append(contributors, ns.String())
+		for j, ip := range ipsByServer[i] {
+			s := sortedIPStrings([]net.IP{ip})[0]
+			if _, ok := ips[s]; !ok {
+				ips[s] = ip
+				seen = append(seen, s)
+			}
+			sources[s] = append(sources[s], ns.String())
+			if rttsByServer[i] != nil && rttsByServer[i][j] > 0 {
+				if rtt, ok := rtts[s]; !ok || rttsByServer[i][j] < rtt {
+					rtts[s] = rttsByServer[i][j]
+				}
+			}
+		}
+	}
+
+	if len(contributors) == 0 {
+		r.logError("Failed to resolve %s via any of the %d servers: Error - '%s'\n", hostname, len(r.servers), shortError(firstErr))
+		result.Err = newResolveError(hostname, firstErr)
+		return result
+	}
+	result.Server = strings.Join(contributors, ", ")
+
+	addrs := seen
+	orderStr := ""
+	if !r.sortByRTT {
+		sort.Strings(addrs)
+	} else {
+		// from the servers' order, so the addresses without an RTT keep it
+		sortAddrsByRTT(addrs, rtts)
+		orderStr = " (ordered by query RTT, a heuristic)"
+	}
+	attributed := make([]string, len(addrs))
+	for i, s := range addrs {
+		result.IPs = append(result.IPs, ips[s])
+		if rtt, ok := rtts[s]; ok {
+			attributed[i] = fmt.Sprintf("%s (via %s; RTT %s)", s, strings.Join(sources[s], ", "), formatDuration(rtt))
+		} else {
+			attributed[i] = fmt.Sprintf("%s (via %s)", s, strings.Join(sources[s], ", "))
+		}
+	}
+	if !r.rawIPv6 {
+		result.IPs = unmapIPv4(result.IPs)
+	}
+	r.logInfo("Merged %d unique address(es) for %s from %d of %d servers%s: %s\n", len(addrs), r.displayName(hostname), len(contributors), len(r.servers), orderStr, strings.Join(attributed, "; "))
+	return result
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// the Nagios/Icinga plugin states, which are the exit status in `-nagios` mode
+const (
+	nagiosOK = iota
+	nagiosWarning
+	nagiosCritical
+	nagiosUnknown
+)
+
+var nagiosStateNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// `-nagios`: stdout carries only the plugin's status line for the single
+// hostname, e.g. `DNS OK - a.example.com resolved to 192.0.2.1 | time=12ms;200;500;0`,
+// written on exit (see `exit`) as the exit status is the state
+type nagiosWriter struct {
+	w          io.Writer
+	warn, crit time.Duration // the latency thresholds; 0 for none
+	checked    bool          // whether the summary was written
+	state      int
+	text       string // the status text, without the performance data
+	perfData   string
+}
+
+func (nw *nagiosWriter) WriteResult(result *ResolveResult) {}
+
+func (nw *nagiosWriter) WriteSummary(summary *Summary) {
+	nw.checked = true
+	if len(summary.Results) == 0 {
+		nw.state = nagiosUnknown
+		nw.text = "no result"
+		if summary.Reason != "" {
+			nw.text += " (" + summary.Reason + ")"
+		}
+		return
+	}
+	nw.state, nw.text, nw.perfData = nw.check(summary.Results[0])
+}
+
+// Write the status line and exit with the state, given the run's exit status
+// `code` (see `exitCode`): every exit in `-nagios` mode must be a plugin
+// state, so a run exiting before the check (e.g. on an unreadable file) is
+// UNKNOWN, or CRITICAL when none of the servers responded, and a failure the
+// check didn't see (e.g. an unmet expectation) makes it CRITICAL
+func (nw *nagiosWriter) exit(code int) {
+	state, line := nw.status(code)
+	nw.writeStatus(line)
+	os.Exit(state)
+}
+
+// the state and status line `exit` reports for `code`
+func (nw *nagiosWriter) status(code int) (int, string) {
+	state, text := nw.state, nw.text
+	switch {
+	case !nw.checked:
+		state = nagiosUnknown
+		if code == exitUnreachable {
+			state = nagiosCritical
+		}
+		text = nagiosReason(code)
+	case code != 0 && state < nagiosCritical:
+		state = nagiosCritical
+		text += " (" + nagiosReason(code) + ")"
+	}
+	line := fmt.Sprintf("DNS %s - %s", nagiosStateNames[state], text)
+	if nw.perfData != "" {
+		line += " | " + nw.perfData
+	}
+	return state, line
+}
+
+// Write the status line and exit UNKNOWN, for invalid arguments
+func (nw *nagiosWriter) exitUsage() {
+	nw.writeStatus(fmt.Sprintf("DNS %s - %s", nagiosStateNames[nagiosUnknown], nagiosReason(exitFailure)))
+	os.Exit(nagiosUnknown)
+}
+
+func (nw *nagiosWriter) writeStatus(line string) {
+	if _, err := fmt.Fprintln(nw.w, line); err != nil {
+		LogError("Failed to write the status line: %s\n", err.Error())
+	}
+}
+
+// the status text for a run exiting with `code` without a check to report:
+// the last error logged, which explains it
+func nagiosReason(code int) string {
+	if reason := LastError(); reason != "" {
+		return reason
+	}
+	return fmt.Sprintf("exited with status %d", code)
+}
+
+// The state, status text and performance data for `result`: CRITICAL when it
+// failed or took `crit` or longer, WARNING when it took `warn` or longer, OK
+// otherwise
+func (nw *nagiosWriter) check(result *ResolveResult) (int, string, string) {
+	perfData := fmt.Sprintf("time=%dms;%s;%s;0", result.Duration.Milliseconds(), nagiosThreshold(nw.warn), nagiosThreshold(nw.crit))
+	if result.Err != nil {
+		return nagiosCritical, fmt.Sprintf("%s: %s", result.Hostname, shortError(result.Err)), perfData
+	}
+
+	state := nagiosOK
+	var exceeded string
+	switch {
+	case nw.crit > 0 && result.Duration >= nw.crit:
+		state = nagiosCritical
+		exceeded = fmt.Sprintf(" in %s (critical at %s)", formatDuration(result.Duration), formatDuration(nw.crit))
+	case nw.warn > 0 && result.Duration >= nw.warn:
+		state = nagiosWarning
+		exceeded = fmt.Sprintf(" in %s (warning at %s)", formatDuration(result.Duration), formatDuration(nw.warn))
+	}
+	addrs := make([]string, len(result.IPs))
+	for i, ip := range result.IPs {
+		addrs[i] = ip.String()
+	}
+	return state, fmt.Sprintf("%s resolved to %s%s", result.Hostname, strings.Join(addrs, ", "), exceeded), perfData
+}
+
+// a threshold in the performance data, in ms; empty when unset
+func nagiosThreshold(threshold time.Duration) string {
+	if threshold <= 0 {
+		return ""
+	}
+	return fmt.Sprint(threshold.Milliseconds())
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNagiosStatus(t *testing.T) {
+	ok := &ResolveResult{Hostname: "ok.test", IPs: []net.IP{net.ParseIP("192.0.2.1")}, Duration: 12 * time.Millisecond}
+	slow := &ResolveResult{Hostname: "ok.test", IPs: []net.IP{net.ParseIP("192.0.2.1")}, Duration: 300 * time.Millisecond}
+	failed := &ResolveResult{
+		Hostname: "nxdomain.test",
+		Err:      newResolveError("nxdomain.test", &net.DNSError{Err: "no such host", Name: "nxdomain.test", IsNotFound: true}),
+		Duration: 5 * time.Millisecond,
+	}
+	tests := []struct {
+		name      string
+		result    *ResolveResult // nil when exiting before the check
+		lastError string
+		code      int
+		wantState int
+		wantLine  string
+	}{
+		{"ok", ok, "", 0, nagiosOK, "DNS OK - ok.test resolved to 192.0.2.1 | time=12ms;200;500;0"},
+		{"warning", slow, "", 0, nagiosWarning, "DNS WARNING - ok.test resolved to 192.0.2.1 in 300 ms (warning at 200 ms) | time=300ms;200;500;0"},
+		{"failed", failed, "", exitAllFailed, nagiosCritical, "DNS CRITICAL - nxdomain.test: no such host | time=5ms;200;500;0"},
+		{"unmet expectation", ok, "FAIL ok.test", exitFailure, nagiosCritical, "DNS CRITICAL - ok.test resolved to 192.0.2.1 (FAIL ok.test) | time=12ms;200;500;0"},
+		{"unreachable", nil, "DNS server unreachable", exitUnreachable, nagiosCritical, "DNS CRITICAL - DNS server unreachable"},
+		{"early failure", nil, "Failed to read input file 'x'", 1, nagiosUnknown, "DNS UNKNOWN - Failed to read input file 'x'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := lastError.Load()
+			t.Cleanup(func() { lastError.Store(saved) })
+			lastError.Store(&tt.lastError)
+
+			nw := &nagiosWriter{warn: 200 * time.Millisecond, crit: 500 * time.Millisecond}
+			if tt.result != nil {
+				nw.WriteSummary(&Summary{Results: []*ResolveResult{tt.result}})
+			}
+			state, line := nw.status(tt.code)
+			if state != tt.wantState || line != tt.wantLine {
+				t.Errorf("got %s %q, want %s %q", nagiosStateNames[state], line, nagiosStateNames[tt.wantState], tt.wantLine)
+			}
+		})
+	}
+}
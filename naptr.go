@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Look up the NAPTR records for `hostname` (e.g. an ENUM domain) via the
+// lower-level client, as `net.Resolver` has no NAPTR support, logging them
+// sorted by order and then preference. A name without NAPTR records logs
+// "none" rather than failing
+func (r *Resolver) ResolveNAPTR(ctx context.Context, hostname string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(hostname), dns.TypeNAPTR)
+	var resp *dns.Msg
+	queryCtx, answered := withServerRecorder(ctx)
+	ns, err := r.withFailover(queryCtx, func(ns nameServer) error {
+		var err error
+		resp, err = r.exchange(queryCtx, ns, msg)
+		return err
+	})
+	result.Server = answered.serverOr(ns)
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		r.logError("Failed to look up NAPTR records for %s via %s: Error - '%s'\n", hostname, result.Server, err.Error())
+		result.Err = newResolveError(hostname, err)
+		return result
+	}
+
+	var records []*dns.NAPTR
+	for _, rr := range resp.Answer {
+		if naptr, ok := rr.(*dns.NAPTR); ok {
+			records = append(records, naptr)
+		}
+	}
+	if len(records) == 0 {
+		r.logInfo("NAPTR records for %s via %s: none\n", r.displayName(hostname), result.Server)
+		return result
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Order != records[j].Order {
+			return records[i].Order < records[j].Order
+		}
+		return records[i].Preference < records[j].Preference
+	})
+
+	r.logInfo("NAPTR records for %s via %s: %d\n", r.displayName(hostname), result.Server, len(records))
+	for _, naptr := range records {
+		replacement := naptr.Replacement
+		if replacement != "." {
+			// "." means no replacement, so leave it as is
+			replacement = r.displayName(replacement)
+		}
+		record := fmt.Sprintf("order=%d preference=%d flags=%q service=%q regexp=%q replacement=%s",
+			naptr.Order, naptr.Preference, naptr.Flags, naptr.Service, naptr.Regexp, replacement)
+		result.Records = append(result.Records, record)
+		r.logInfo("  %s\n", record)
+	}
+	return result
+}
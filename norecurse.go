@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Query `hostname` with Recursion Desired unset, so the server answers only from
+// its own data or authority: typically a referral (NS records in the authority
+// section, with glue) when queried for a name it isn't authoritative for
+func (r *Resolver) ResolveNoRecurse(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	for _, qtype := range queryTypes(network) {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(hostname), qtype)
+		msg.RecursionDesired = false
+
+		var resp *dns.Msg
+		ns, err := r.withFailover(ctx, func(ns nameServer) error {
+			var err error
+			resp, err = r.exchange(ctx, ns, msg)
+			return err
+		})
+		result.Server = ns.String()
+		if err != nil {
+			r.logError("Failed to query (no recursion): %s %s via %s Error - '%s'\n", hostname, dns.TypeToString[qtype], ns, err.Error())
+			result.Err = newResolveError(hostname, err)
+			break
+		}
+
+		if len(resp.Answer) > 0 {
+			result.IPs = append(result.IPs, addrsFromRRs(resp.Answer)...)
+			r.logInfo("Answer for %s %s from %s (authoritative: %t): %s\n", hostname, dns.TypeToString[qtype], ns, resp.Authoritative, rrStrings(resp.Answer))
+			continue
+		}
+
+		var referral []dns.RR
+		for _, rr := range resp.Ns {
+			if _, ok := rr.(*dns.NS); ok {
+				referral = append(referral, rr)
+			}
+		}
+		if len(referral) == 0 {
+			r.logInfo("No answer or referral for %s %s from %s\n", hostname, dns.TypeToString[qtype], ns)
+			continue
+		}
+		r.logInfo("Referral for %s %s from %s to zone %s: %s\n", hostname, dns.TypeToString[qtype], ns, referral[0].Header().Name, rrStrings(referral))
+		if glue := glueRecords(resp.Extra); len(glue) > 0 {
+			r.logInfo("Glue for the referral from %s: %s\n", ns, rrStrings(glue))
+		}
+	}
+
+	result.Duration = time.Since(startTime)
+	r.logInfo("Duration for querying %s: %s\n", hostname, formatDuration(result.Duration))
+	return result
+}
+
+func glueRecords(rrs []dns.RR) []dns.RR {
+	var glue []dns.RR
+	for _, rr := range rrs {
+		switch rr.(type) {
+		case *dns.A, *dns.AAAA:
+			glue = append(glue, rr)
+		}
+	}
+	return glue
+}
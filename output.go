@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// the underlying reason for `err`, without the "lookup x on y:53:" noise
+func shortError(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.Err
+	}
+	var resolveErr *ResolveError
+	if errors.As(err, &resolveErr) {
+		return resolveErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// the reverse names for each of the result's addresses, in address order
+func reverseNames(result *ResolveResult) []string {
+	var names []string
+	for _, ip := range result.IPs {
+		names = append(names, result.Reverse[ip.String()]...)
+	}
+	return names
+}
+
+// single-line rendering of a result: `hostname: [ips] (reverse) 12ms`
+func (r *Resolver) compactLine(result *ResolveResult) string {
+	duration := formatCompactDuration(result.Duration)
+	hostname := r.displayName(result.Hostname)
+	if result.Err != nil {
+		return fmt.Sprintf("%s: FAILED (%s) %s", hostname, shortError(result.Err), duration)
+	}
+
+	line := fmt.Sprintf("%s: [%s]", hostname, r.limitAddrs(result.IPs))
+	if names := reverseNames(result); len(names) > 0 {
+		line += fmt.Sprintf(" (%s)", r.limitNames(names))
+	}
+	if len(r.servers) > 1 {
+		// tag which of the servers answered
+		line += " via " + result.Server
+	}
+	return fmt.Sprintf("%s %s", line, duration)
+}
+
+// Writes each result as a line of JSON as soon as it completes; results
+// arrive from concurrent goroutines, so writes are serialized
+type jsonlWriter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+	r  *Resolver
+}
+
+func newJSONLWriter(w io.Writer, r *Resolver) *jsonlWriter {
+	return &jsonlWriter{w: bufio.NewWriter(w), r: r}
+}
+
+func (jw *jsonlWriter) WriteResult(result *ResolveResult) {
+	data, err := json.Marshal(newHostnameReport(result, jw.r))
+	if err != nil {
+		LogError("Failed to encode result for %s: %s\n", result.Hostname, err.Error())
+		return
+	}
+
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+	jw.w.Write(append(data, '\n'))
+	if err := jw.w.Flush(); err != nil {
+		LogError("Failed to write result for %s: %s\n", result.Hostname, err.Error())
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// A filesystem-safe file name for `hostname`: anything other than letters,
+// digits, '.', '-' and '_' is replaced with '_'. Names that changed doing so,
+// or with upper-case letters, get a short hash of the hostname appended, so
+// two hostnames never share a file (e.g. `a b.com` and `a_b.com`, or case
+// variants on a case-insensitive filesystem) whatever order they complete in
+func sanitizeFilename(hostname string) string {
+	hostname = strings.TrimSuffix(hostname, ".")
+	name := strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '-', c == '_':
+			return c
+		default:
+			return '_'
+		}
+	}, hostname)
+	// keep clear of ".", ".." and hidden files
+	if strings.HasPrefix(name, ".") || name == "" {
+		name = "_" + name
+	}
+	if name != strings.ToLower(hostname) {
+		sum := sha256.Sum256([]byte(hostname))
+		name += "-" + hex.EncodeToString(sum[:4])
+	}
+	return name
+}
+
+// write `result` as JSON to `<dir>/<hostname>.json`
+func writeResultFile(dir string, result *ResolveResult, r *Resolver) error {
+	data, err := json.MarshalIndent(newHostnameReport(result, r), "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, sanitizeFilename(result.Hostname)+".json")
+	return writeFileAtomic(path, append(data, '\n'))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		hostname string
+		want     string
+	}{
+		{"www.example.com", "www.example.com"},
+		{"www.example.com.", "www.example.com"},
+		{"_sip._tcp.example.com", "_sip._tcp.example.com"},
+		{"WWW.example.com", "WWW.example.com-a1dff13b"},
+		{"a b.example.com", "a_b.example.com-efe54776"},
+		{"a/b.example.com", "a_b.example.com-c8cf7bd3"},
+		{"..", "_.-cdb4ee2a"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeFilename(tt.hostname); got != tt.want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", tt.hostname, got, tt.want)
+		}
+	}
+}
+
+func TestWriteResultFileCollisions(t *testing.T) {
+	dir := t.TempDir()
+	r := &Resolver{}
+	// each maps to a_b.example.com when sanitized, or differs only in case
+	hostnames := []string{"a_b.example.com", "a b.example.com", "a/b.example.com", "A_B.example.com"}
+	for _, hostname := range hostnames {
+		if err := writeResultFile(dir, &ResolveResult{Hostname: hostname}, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(hostnames) {
+		t.Errorf("got %d files, want one per hostname", len(files))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a_b.example.com.json")); err != nil {
+		t.Errorf("the unchanged name isn't kept: %v", err)
+	}
+}
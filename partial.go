@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// With `-partial-ok` in dual-stack (`ip`) mode, a failed lookup is retried per
+// family (A, then AAAA), so a hostname counts as resolved when at least one
+// family does; returns the addresses found and the error for the family that
+// failed. By default the lookups are strict: an error for either family fails
+// the hostname (e.g. a SERVFAIL for the AAAA records of an IPv4-only host)
+func (r *Resolver) lookupEachFamily(ctx context.Context, name string) ([]net.IP, nameServer, error) {
+	var ips []net.IP
+	var ns nameServer
+	var familyErr error
+	for _, family := range []NetworkString{IPv4, IPv6} {
+		familyIPs, familyNS, err := r.lookupIP(ctx, family, name)
+		if err != nil {
+			familyErr = fmt.Errorf("%s lookup: %w", queryTypeNames(family), err)
+			continue
+		}
+		ips = append(ips, familyIPs...)
+		ns = familyNS
+	}
+	return ips, ns, familyErr
+}
+
+// whether a partial result may be salvaged from the dual-stack lookup's `err`;
+// a name that doesn't exist won't resolve for either family
+func (r *Resolver) tryPartial(network NetworkString, err error) bool {
+	var dnsErr *net.DNSError
+	return r.partialOK && network == IP && err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound)
+}
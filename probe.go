@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Query each configured server for the root's NS records within `timeout`, so
+// a server that's down fails the run at once rather than every hostname in
+// turn. Any response counts as reachable, whatever its rcode; only a timeout
+// or a connection error doesn't. Unreachable servers are warned about when
+// another one answers, and `ErrServerUnreachable` is returned when none does.
+// The system's default resolver isn't probed
+func (r *Resolver) ProbeServers(ctx context.Context, timeout time.Duration) error {
+	errs := make([]error, len(r.servers))
+	var wg sync.WaitGroup
+	for i, ns := range r.servers {
+		if ns.addr == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			msg := new(dns.Msg)
+			msg.SetQuestion(".", dns.TypeNS)
+			_, err := r.exchangeOver(probeCtx, ns, msg, "udp")
+			var rcodeErr *RcodeError
+			if err != nil && !errors.As(err, &rcodeErr) {
+				errs[i] = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	var unreachable int
+	for i, err := range errs {
+		if err != nil {
+			unreachable++
+			r.logWarning("DNS server %s is unreachable: '%s'\n", r.servers[i], shortError(err))
+		}
+	}
+	if unreachable > 0 && unreachable == len(r.servers) {
+		return fmt.Errorf("%w: no response from %d server(s) within %s", ErrServerUnreachable, unreachable, formatDuration(timeout))
+	}
+	return nil
+}
+
+// Replace the configured servers with the system's default resolver, e.g.
+// when none of them is reachable
+func (r *Resolver) FallBackToDefault() {
+	r.servers = []nameServer{r.systemServer()}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// Start a CPU profile written to `cpuPath`, if set, returning the func that
+// stops it and writes a heap profile to `memPath`, if set; it must be called
+// before exiting, as `os.Exit` skips deferred calls. A profile that can't be
+// written is logged rather than failing the run
+func startProfiles(cpuPath, memPath string) (stop func()) {
+	var cpuFile *os.File
+	if cpuPath != "" {
+		f, err := os.Create(cpuPath)
+		if err == nil {
+			err = pprof.StartCPUProfile(f)
+		}
+		if err != nil {
+			LogError("Failed to start CPU profile '%s': %s\n", cpuPath, err.Error())
+		} else {
+			cpuFile = f
+		}
+	}
+
+	return func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			if err := cpuFile.Close(); err != nil {
+				LogError("Failed to write CPU profile '%s': %s\n", cpuPath, err.Error())
+			}
+		}
+		if memPath != "" {
+			if err := writeHeapProfile(memPath); err != nil {
+				LogError("Failed to write memory profile '%s': %s\n", memPath, err.Error())
+			}
+		}
+	}
+}
+
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// up-to-date statistics, including the garbage collected since the last cycle
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const dnsPort = "53"
+
+// address (host:port) for queries sent via the raw client; the system's first
+// configured nameserver stands in for the default resolver
+func (ns nameServer) rawAddr() (string, error) {
+	if ns.addr != "" {
+		return ns.hostPort(), nil
+	}
+	conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil {
+		return "", err
+	}
+	if len(conf.Servers) == 0 {
+		return "", errors.New("no nameservers configured in /etc/resolv.conf")
+	}
+	return net.JoinHostPort(conf.Servers[0], conf.Port), nil
+}
+
+// Send `msg` to `ns` via the lower-level client (for what `net.Resolver` can't
+// express), retrying over TCP when the UDP response is truncated. A response
+// with a non-success rcode is returned along with an `*RcodeError`
+func (r *Resolver) exchange(ctx context.Context, ns nameServer, msg *dns.Msg) (*dns.Msg, error) {
+	resp, err := r.exchangeOver(ctx, ns, msg, "udp")
+	if err == nil && resp.Truncated {
+		resp, err = r.exchangeOver(ctx, ns, msg, "tcp")
+	}
+	return resp, err
+}
+
+// Send `msg` to `ns` over `transport` (udp|tcp) only, returning a truncated
+// UDP response as is
+func (r *Resolver) exchangeOver(ctx context.Context, ns nameServer, msg *dns.Msg, transport string) (*dns.Msg, error) {
+	addr, err := ns.rawAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.txid != nil {
+		// for testing only: a fixed ID makes responses predictable
+		msg = msg.Copy()
+		msg.Id = *r.txid
+	}
+	if r.ecs != nil {
+		msg = r.withECS(msg)
+	}
+	if r.use0x20 {
+		msg = randomizeCase(msg)
+	}
+
+	recordServer(ctx, addr)
+	client := &dns.Client{Net: transport, Dialer: r.dialer(transport)}
+	var resp *dns.Msg
+	var rtt time.Duration
+	if transport == "tcp" && r.tcpPool != nil {
+		resp, rtt, err = r.exchangePooled(ctx, client, msg, addr)
+	} else {
+		resp, rtt, err = client.ExchangeContext(ctx, msg, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	recordRTT(ctx, rtt)
+	if r.txid != nil {
+		r.logInfo("Response to %s %s (id %d) from %s: %s, %d answer(s)\n", msg.Question[0].Name, dns.TypeToString[msg.Question[0].Qtype], resp.Id, addr, dns.RcodeToString[resp.Rcode], len(resp.Answer))
+	}
+	if r.ecs != nil {
+		r.logECSScope(addr, msg.Question[0].Name, resp)
+	}
+	if r.use0x20 {
+		r.check0x20(addr, msg, resp)
+	}
+	r.checkResponseSize(addr, msg, resp)
+	if resp.Rcode != dns.RcodeSuccess {
+		return resp, &RcodeError{Rcode: resp.Rcode}
+	}
+	return resp, nil
+}
+
+// Forward lookup of `name` via the lower-level client rather than
+// `net.Resolver`, for when the queries themselves must be controlled
+// (e.g. `-txid`, `-ecs`, `-max-response-size`, `-0x20`); an empty answer returns no addresses and no error
+func (r *Resolver) rawLookupIP(ctx context.Context, ns nameServer, network NetworkString, name string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, qtype := range queryTypes(network) {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(name), qtype)
+		resp, err := r.exchange(ctx, ns, msg)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, addrsFromRRs(resp.Answer)...)
+	}
+	return ips, nil
+}
+
+// Log the size of the response to `msg` at debug level, warning when it's
+// larger than `-max-response-size`: a potential amplification source, or a
+// misconfigured zone. The size is that of the response packed with name
+// compression, as servers send it
+func (r *Resolver) checkResponseSize(addr string, msg, resp *dns.Msg) {
+	resp.Compress = true
+	size := resp.Len()
+	q := msg.Question[0]
+	r.logDebug("Response to %s %s from %s: %d bytes\n", q.Name, dns.TypeToString[q.Qtype], addr, size)
+	if r.maxResponseSize > 0 && size > r.maxResponseSize {
+		r.logWarning("Response to %s %s from %s is %d bytes, above the maximum of %d (-max-response-size)\n", q.Name, dns.TypeToString[q.Qtype], addr, size, r.maxResponseSize)
+	}
+}
+
+// Whether the failed lookup of `name` via `ns` got NXDOMAIN rather than an
+// empty (NODATA) answer: the Go resolver reports both as not found, so the
+// name is queried again via the lower-level client to tell them apart
+func (r *Resolver) isNXDomain(ctx context.Context, ns nameServer, name string, err error) bool {
+	var rcodeErr *RcodeError
+	if errors.As(err, &rcodeErr) {
+		return rcodeErr.Rcode == dns.RcodeNameError
+	}
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		return false
+	}
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), dns.TypeA)
+	_, err = r.exchange(ctx, ns, msg)
+	return errors.As(err, &rcodeErr) && rcodeErr.Rcode == dns.RcodeNameError
+}
+
+// whether the forward lookups must go via the lower-level client, as their
+// queries need what `net.Resolver` can't express
+func (r *Resolver) rawForward() bool {
+	return r.txid != nil || r.ecs != nil || r.maxResponseSize > 0 || r.use0x20
+}
+
+// the query types for the `network` (ip4|ip6|ip)
+func queryTypes(network NetworkString) []uint16 {
+	switch network {
+	case IPv4:
+		return []uint16{dns.TypeA}
+	case IPv6:
+		return []uint16{dns.TypeAAAA}
+	default:
+		return []uint16{dns.TypeA, dns.TypeAAAA}
+	}
+}
+
+// the addresses from the A/AAAA records in `rrs`
+func addrsFromRRs(rrs []dns.RR) []net.IP {
+	var ips []net.IP
+	for _, rr := range rrs {
+		switch rec := rr.(type) {
+		case *dns.A:
+			ips = append(ips, rec.A)
+		case *dns.AAAA:
+			ips = append(ips, rec.AAAA)
+		}
+	}
+	return ips
+}
+
+// render the records in `rrs` one per entry, without the header's TTL and class noise
+func rrStrings(rrs []dns.RR) string {
+	strs := make([]string, len(rrs))
+	for i, rr := range rrs {
+		strs[i] = strings.TrimPrefix(rr.String(), rr.Header().String())
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestFixedTxid(t *testing.T) {
+	ts := newTestServer(t)
+	r := newTestResolver(t, ts, nil)
+	id := uint16(0)
+	r.txid = &id
+	r.firstIP = true // no reverse lookups, whose queries don't go via the lower-level client
+	if !r.rawForward() {
+		t.Fatal("a fixed txid doesn't send the queries via the lower-level client")
+	}
+	for range 3 {
+		result := r.ResolveHostname(testContext(t), IPv4, "v4.test")
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if got := ts.lastID.Load(); got != 0 {
+			t.Errorf("got id %d, want 0", got)
+		}
+	}
+}
+
+// the zero value `Resolver` (without any of the constructors) uses random IDs
+func TestRandomTxid(t *testing.T) {
+	ts := newTestServer(t)
+	r := &Resolver{servers: newTestResolver(t, ts, nil).servers, noLog: true}
+	if r.rawForward() {
+		t.Error("the zero value Resolver sends its queries via the lower-level client")
+	}
+	ids := map[uint32]bool{}
+	for range 4 {
+		msg := new(dns.Msg)
+		msg.SetQuestion("v4.test.", dns.TypeA)
+		if _, err := r.exchange(testContext(t), r.servers[0], msg); err != nil {
+			t.Fatal(err)
+		}
+		ids[ts.lastID.Load()] = true
+	}
+	if len(ids) < 2 {
+		t.Errorf("got the same id for every query: %v", ids)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Attempt a TCP connect to each of a resolved hostname's addresses on the
+// configured port, concurrently and each with its own timeout, recording
+// the outcome per address (a nil error when reachable)
+func (r *Resolver) checkPort(ctx context.Context, result *ResolveResult) {
+	if r.checkPortNum <= 0 || len(result.IPs) == 0 {
+		return
+	}
+	port := strconv.Itoa(r.checkPortNum)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result.PortErrs = make(map[string]error, len(result.IPs))
+	for _, ip := range result.IPs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dialCtx, cancel := context.WithTimeout(ctx, r.checkPortTimeout)
+			defer cancel()
+
+			addr := net.JoinHostPort(ip.String(), port)
+			start := time.Now()
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(dialCtx, "tcp", addr)
+			if err == nil {
+				conn.Close()
+				r.logInfo("%s: %s reachable (%s)\n", r.displayName(result.Hostname), addr, formatDuration(time.Since(start)))
+			} else {
+				r.logWarning("%s: %s unreachable: '%s'\n", r.displayName(result.Hostname), addr, err.Error())
+			}
+
+			mu.Lock()
+			result.PortErrs[ipString(ip)] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
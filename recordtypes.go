@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// A record type selectable via `-type`, with the lookup implementing it
+type recordType struct {
+	name        string
+	description string
+	// resolves a hostname for this type; nil for addresses, which go through
+	// the regular flow (and its modes, e.g. -diff-default)
+	lookup func(r *Resolver, ctx context.Context, network NetworkString, hostname string) *ResolveResult
+}
+
+// the record types that can be looked up, listed by `-list-types`
+var recordTypes = []*recordType{
+	{
+		name:        "addr",
+		description: "IPv4 and/or IPv6 addresses (A/AAAA, per -iptype) with their reverse names (default)",
+	},
+	{
+		name:        "naptr",
+		description: "NAPTR records (e.g. ENUM/SIP), sorted by order and then preference",
+		lookup: func(r *Resolver, ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+			return r.ResolveNAPTR(ctx, hostname)
+		},
+	},
+	{
+		name:        "txt",
+		description: "TXT records, in the -class given (e.g. -class ch for version.bind)",
+		lookup: func(r *Resolver, ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+			return r.ResolveTXT(ctx, hostname)
+		},
+	},
+	{
+		name:        "dnskey",
+		description: "DNSKEY records (DNSSEC), with their key tags; a zone without them is reported as unsigned",
+		lookup: func(r *Resolver, ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+			return r.ResolveDNSSEC(ctx, hostname, dns.TypeDNSKEY)
+		},
+	},
+	{
+		name:        "ds",
+		description: "DS records (DNSSEC) from the parent zone, with the key tags of the DNSKEYs they cover",
+		lookup: func(r *Resolver, ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+			return r.ResolveDNSSEC(ctx, hostname, dns.TypeDS)
+		},
+	},
+	{
+		name:        "tlsa",
+		description: "TLSA records (DANE) for the service on the -port and -proto given, e.g. _443._tcp.<hostname>",
+		lookup: func(r *Resolver, ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+			return r.ResolveTLSA(ctx, hostname)
+		},
+	},
+}
+
+// the record type named `name` (case-insensitive)
+func lookupRecordType(name string) (*recordType, error) {
+	for _, t := range recordTypes {
+		if strings.EqualFold(t.name, name) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported record type '%s' (see -list-types)", name)
+}
+
+func recordTypeNames() string {
+	names := make([]string, len(recordTypes))
+	for i, t := range recordTypes {
+		names[i] = t.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// print each supported record type and its description
+func listRecordTypes(w io.Writer) {
+	for _, t := range recordTypes {
+		fmt.Fprintf(w, "%-8s %s\n", t.name, t.description)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// per-hostname entry of the `-report-file` JSON
+type hostnameReport struct {
+	Hostname      string              `json:"hostname"`
+	QueryName     string              `json:"query_name,omitempty"`
+	Status        string              `json:"status"` // "resolved" or "failed"
+	IPs           []string            `json:"ips,omitempty"`
+	Reverse       map[string][]string `json:"reverse,omitempty"`
+	Records       []string            `json:"records,omitempty"`
+	Error         string              `json:"error,omitempty"`
+	ReverseErrors []string            `json:"reverse_errors,omitempty"`
+	LatencyError  string              `json:"latency_error,omitempty"`
+	TTLError      string              `json:"ttl_error,omitempty"`
+	PartialError  string              `json:"partial_error,omitempty"`
+	Port          map[string]string   `json:"port,omitempty"` // "reachable" or the connect error, keyed by IP address
+	Server        string              `json:"server,omitempty"`
+	DurationMs    int64               `json:"duration_ms"`
+}
+
+// machine-readable summary of a run, written via `-report-file`
+type runReport struct {
+	Hostnames   []hostnameReport `json:"hostnames"`
+	Total       int              `json:"total"`
+	Resolved    int              `json:"resolved"`
+	Failed      int              `json:"failed"`
+	ServFail    int              `json:"servfail"`
+	Servers     []string         `json:"servers"`
+	DurationMs  int64            `json:"duration_ms"`
+	Interrupted bool             `json:"interrupted"`
+}
+
+func newRunReport(results []*ResolveResult, r *Resolver, duration time.Duration) *runReport {
+	report := &runReport{
+		Hostnames:  make([]hostnameReport, 0, len(results)),
+		Total:      len(results),
+		Servers:    r.ServerAddrs(),
+		DurationMs: duration.Milliseconds(),
+	}
+	for _, result := range results {
+		entry := newHostnameReport(result, r)
+		if result.Err == nil {
+			report.Resolved++
+		}
+		if r.Failed(result) {
+			report.Failed++
+		}
+		if isServFailResult(result) {
+			report.ServFail++
+		}
+		report.Hostnames = append(report.Hostnames, entry)
+	}
+	return report
+}
+
+func newHostnameReport(result *ResolveResult, r *Resolver) hostnameReport {
+	entry := hostnameReport{
+		Hostname:   result.Hostname,
+		QueryName:  result.QueryName,
+		Status:     "resolved",
+		Server:     result.Server,
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	if len(result.Reverse) > 0 {
+		entry.Reverse = result.Reverse
+	}
+	entry.Records = result.Records
+	for _, ip := range result.IPs {
+		entry.IPs = append(entry.IPs, ipString(ip))
+	}
+	if result.Err != nil {
+		entry.Error = result.Err.Error()
+	}
+	for _, err := range result.ReverseErrs {
+		entry.ReverseErrors = append(entry.ReverseErrors, err.Error())
+	}
+	if result.LatencyErr != nil {
+		entry.LatencyError = result.LatencyErr.Error()
+	}
+	if result.TTLErr != nil {
+		entry.TTLError = result.TTLErr.Error()
+	}
+	if result.PartialErr != nil {
+		entry.PartialError = result.PartialErr.Error()
+	}
+	if len(result.PortErrs) > 0 {
+		entry.Port = make(map[string]string, len(result.PortErrs))
+		for ip, err := range result.PortErrs {
+			entry.Port[ip] = "reachable"
+			if err != nil {
+				entry.Port[ip] = err.Error()
+			}
+		}
+	}
+	if r.Failed(result) {
+		entry.Status = "failed"
+	}
+	return entry
+}
+
+// write the report to `path`, via a temporary file so a reader never sees
+// a partial one
+func (report *runReport) write(path string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
@@ -0,0 +1,131 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// A dialer Control func refusing to dial unless the socket's traffic would
+// egress via one of the `allowed` interfaces (e.g. a VPN's), so the queries
+// can't leak onto another network: a socket bound to a device (`-bind-device`)
+// is checked against that device, otherwise against the interface the kernel
+// routes the address via from `source` (`-source-ip`, if set), as source-based
+// policy routing may pick another route than from the default source. Failing
+// to tell refuses the dial too
+func requireInterfaceControl(allowed []string, source net.IP) (func(network, address string, c syscall.RawConn) error, error) {
+	return func(network, address string, c syscall.RawConn) error {
+		var device string
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			device, sockErr = unix.GetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE)
+		}); err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return fmt.Errorf("%w: can't tell the socket's device: %w", ErrInterfaceNotAllowed, sockErr)
+		}
+		if device == "" {
+			var err error
+			if device, err = routeInterface(address, source); err != nil {
+				return fmt.Errorf("%w: can't tell the route to %s: %w", ErrInterfaceNotAllowed, address, err)
+			}
+		}
+		if !slices.Contains(allowed, device) {
+			return fmt.Errorf("%w: %s goes via %s", ErrInterfaceNotAllowed, address, device)
+		}
+		return nil
+	}, nil
+}
+
+// the interface the kernel routes `address` (ip:port) via: connecting a UDP
+// socket picks the route, and so the source address, without sending
+// anything, and the interface is the one holding that address. The socket is
+// bound to `source` first, if set (and of the address's family), as the
+// queries' sockets are. A scoped IPv6 address (fe80::1%eth0) goes via its
+// zone's interface
+func routeInterface(address string, source net.IP) (string, error) {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return "", err
+	}
+	ip := addrPort.Addr().Unmap()
+	if zone := ip.Zone(); zone != "" {
+		if index, err := strconv.Atoi(zone); err == nil {
+			iface, err := net.InterfaceByIndex(index)
+			if err != nil {
+				return "", err
+			}
+			return iface.Name, nil
+		}
+		return zone, nil
+	}
+
+	family := unix.AF_INET6
+	var sa unix.Sockaddr = &unix.SockaddrInet6{Port: int(addrPort.Port()), Addr: ip.As16()}
+	if ip.Is4() {
+		family = unix.AF_INET
+		sa = &unix.SockaddrInet4{Port: int(addrPort.Port()), Addr: ip.As4()}
+	}
+	fd, err := unix.Socket(family, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return "", err
+	}
+	defer unix.Close(fd)
+	if source != nil {
+		var local unix.Sockaddr
+		if source4 := source.To4(); source4 != nil && ip.Is4() {
+			local = &unix.SockaddrInet4{Addr: [4]byte(source4)}
+		} else if source4 == nil && ip.Is6() {
+			local = &unix.SockaddrInet6{Addr: [16]byte(source.To16())}
+		}
+		if local != nil {
+			if err := unix.Bind(fd, local); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := unix.Connect(fd, sa); err != nil {
+		return "", err
+	}
+	local, err := unix.Getsockname(fd)
+	if err != nil {
+		return "", err
+	}
+
+	var localIP net.IP
+	switch local := local.(type) {
+	case *unix.SockaddrInet4:
+		localIP = net.IP(local.Addr[:])
+	case *unix.SockaddrInet6:
+		localIP = net.IP(local.Addr[:])
+	}
+	return interfaceWithAddr(localIP)
+}
+
+// the name of the interface holding the address `ip`
+func interfaceWithAddr(ip net.IP) (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return "", err
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return iface.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no interface has the source address %s", ip)
+}
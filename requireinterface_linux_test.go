@@ -0,0 +1,51 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+// the route is looked up from the queries' source address, which may pick
+// another interface than the default source's
+func TestRouteInterfaceSource(t *testing.T) {
+	// via the default route, if there's one
+	const address = "198.51.100.1:53"
+	device, err := routeInterface(address, nil)
+	if err != nil {
+		t.Skipf("no route to %s: %v", address, err)
+	}
+	if device == "lo" {
+		t.Skipf("%s is routed via lo", address)
+	}
+
+	// from the loopback address, the default route's interface can't be
+	// used: the route either goes another way or isn't usable at all
+	if got, err := routeInterface(address, net.ParseIP("127.0.0.1")); err == nil && got == device {
+		t.Errorf("got %s from 127.0.0.1, as from the default source", got)
+	}
+	if got, err := routeInterface("127.0.0.1:53", net.ParseIP("127.0.0.1")); err != nil || got != "lo" {
+		t.Errorf("got %q, %v for the loopback address, want lo", got, err)
+	}
+
+	// so requiring the default route's interface fails closed for the queries
+	// from the loopback address
+	control, err := requireInterfaceControl([]string{device}, net.ParseIP("127.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := control("udp4", address, rawConn); !errors.Is(err, ErrInterfaceNotAllowed) {
+		t.Errorf("got %v, want ErrInterfaceNotAllowed", err)
+	}
+}
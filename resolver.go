@@ -1,15 +1,86 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Resolver struct {
-	resolver *net.Resolver
+	servers            []nameServer // tried in order (from a shuffled starting point) until one answers
+	picker             serverPicker
+	quiet              bool          // suppress per-hostname info output; errors are still logged
+	noLog              bool          // suppress all logging, for callers using only the results
+	reverseErrorsFatal bool          // log missing PTR records as errors rather than warnings
+	rawIPv6            bool          // keep IPv4-mapped IPv6 addresses in their mapped form
+	appendDomain       string        // suffix appended to hostnames that aren't already qualified with it
+	allowWildcard      bool          // permit a leftmost `*` label, to test for wildcard records
+	retries            int           // additional attempts for a forward lookup after a transient failure
+	retryOnEmpty       bool          // also retry empty answers, for flaky upstreams
+	backoff            retryBackoff  // delay before each retry
+	fallbackSuffix     string        // domain appended to a hostname that got NXDOMAIN, for a second lookup
+	attemptTimeout     time.Duration // the first attempt's timeout, doubled for each retry (0 for the run's timeout)
+	reverseIgnore      []string      // reverse names under these suffixes are suppressed from the output
+	limit              chan struct{} // caps the hostnames resolved at once, across every `ResolveHostnames` call
+	delay              time.Duration // resolve serially, waiting this long between hostnames (0 for concurrently)
+	noRecurse          bool          // query with Recursion Desired unset, logging referrals
+	resultHooks        []func(*ResolveResult)
+	maxLatency         time.Duration // resolutions slower than this are flagged
+	maxLatencyFatal    bool          // count slow resolutions towards the failures for the run
+	diffDefault        bool          // compare each answer against the system's default resolver
+	axfr               bool          // treat hostnames as zones to transfer
+	trailingDot        string        // "strip" or "keep" the trailing dot of names in the output
+	spfExpand          bool          // treat hostnames as domains whose SPF record to expand
+	reverseFamily      NetworkString // only perform reverse lookups for addresses of this family (ip for both)
+	audit              bool          // check each name for records coexisting with a CNAME
+	minTTL             time.Duration // answer records with a shorter TTL are flagged
+	minTTLFatal        bool          // count short TTLs towards the failures for the run
+	queryTTL           bool          // query the answer records' TTLs even without a minimum
+	showTTL            bool          // log the TTLs, with each hop's along a CNAME chain
+	queryCNAME         bool          // query the CNAME chain of each resolved hostname
+	mergeServers       bool          // query every server and merge their answers, rather than failing over
+	sortByRTT          bool          // order the merged addresses by the RTT of the queries returning them
+	reverseCache       *reverseCache // memoizes reverse lookups by IP when set
+	resultCache        *resultCache  // serves and stores the forward lookups persisted between runs when set
+	sourceIP           net.IP        // local address the queries are sent from, if set
+	idnStrict          bool          // fail IDN hostnames mixing scripts, rather than warning
+	idnAllow           []string      // legitimately multilingual domains, exempt from the mixed-script check
+	sortIPs            bool          // sort the addresses rather than keeping the resolver's order
+	firstIP            bool          // keep only the first address, skipping the reverse lookups
+	dependencies       *dependencies // hostnames skipped when one they depend on failed, if set
+	answerLimit        int           // the addresses and reverse names shown per hostname in the human output (0 for all)
+	explain            bool          // narrate each step of the resolution flow
+	partialOK          bool          // in dual-stack mode, count a hostname as resolved when either family resolves
+	recordType         *recordType   // the record type looked up, if not addresses
+	checkPortNum       int           // TCP port to probe on each resolved address (0 to skip)
+	checkPortTimeout   time.Duration // timeout for each probe's connect
+	fcrdns             bool          // forward-confirm each address's reverse names
+	cacheProbe         bool          // query each hostname twice, comparing the cold and warm latencies
+	compareTransport   bool          // query each hostname over both UDP and TCP, comparing the answers
+	tcpPool            *tcpPool      // reuses the TCP connections of queries via the lower-level client, if set
+	class              uint16        // the query class for TXT lookups (0 for IN)
+	tlsaPort           int           // the service's port for TLSA lookups
+	tlsaProto          string        // the service's protocol (tcp|udp|sctp) for TLSA lookups
+	txid               *uint16       // fixed transaction ID for queries via the lower-level client, for testing; nil for random
+	maxResponseSize    int           // responses to queries via the lower-level client larger than this many bytes are flagged (0 for no maximum)
+	iterative          bool          // resolve from the root ourselves, following the referrals
+	qnameMinimization  bool          // when resolving iteratively, send each server only the labels it needs (RFC 7816)
+	use0x20            bool          // randomize the case of the query names, checking the responses echo it
+	ecs                *net.IPNet    // client subnet sent with queries via the lower-level client (EDNS Client Subnet), if set
+	dialBypassed       sync.Once     // warns (once) that a lookup didn't go through the dial func
+	tracer             trace.Tracer  // creates the spans exported via `EnableTracing`; nil when not tracing
+	// sets socket options for the queries, if set
+	dialControl func(network, address string, c syscall.RawConn) error
 }
 
 type NetworkString string
@@ -21,92 +92,638 @@ const (
 	IPv6 NetworkString = "ip6"
 )
 
-// Use an alternate dialer provided via `dnsServerAddr` string,
+// Outcome of resolving a single hostname
+type ResolveResult struct {
+	Hostname    string
+	QueryName   string // the name actually queried, when it differs from `Hostname`
+	IPs         []net.IP
+	Err         error               // a `*ResolveError` when the forward lookup failed
+	Server      string              // the server that answered the forward lookup
+	Reverse     map[string][]string // reverse names keyed by IP address
+	ReverseErrs []error
+	Duration    time.Duration
+	LatencyErr  error            // set when `Duration` exceeded the configured maximum latency
+	PartialErr  error            // the failed family's error, when resolved via `-partial-ok`
+	Records     []string         // the records found, when looking up a type other than addresses
+	TTL         time.Duration    // the lowest TTL among the answer records, when `HasTTL`
+	HasTTL      bool             // whether the TTLs were queried (`-min-ttl` or the `ttl` column)
+	TTLErr      error            // set when an answer record's TTL was below the configured minimum
+	PortErrs    map[string]error // outcome of the `-check-port` connect keyed by IP address; nil when reachable
+	CNAMEs      []string         // the CNAME chain from the queried name to the canonical name, when looked up
+}
+
+// whether `result` counts towards the failures for the run; reverse lookup
+// failures, slow resolutions and short TTLs only count when configured to be fatal
+func (r *Resolver) Failed(result *ResolveResult) bool {
+	return result.Err != nil ||
+		(r.reverseErrorsFatal && len(result.ReverseErrs) > 0) ||
+		(r.maxLatencyFatal && result.LatencyErr != nil) ||
+		(r.minTTLFatal && result.TTLErr != nil)
+}
+
+// Use an alternate dialer provided via `dnsServerAddrs` strings,
 // specified without the port (53)
-// instead of the default DNS server's address
-func NewResolver(dnsServerAddr string) *Resolver {
-	return &Resolver{
-		resolver: &net.Resolver{
-			PreferGo:     true, // 'false' seems to result in using the default (network's) DNS server, avoiding lookups via the IP address provided
-			StrictErrors: true,
-			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-				d := net.Dialer{}
-				return d.DialContext(ctx, "udp", dnsServerAddr+":53")
-			},
+// instead of the default DNS server's address;
+// with several addresses, each query fails over to the next server in turn
+func NewResolver(dnsServerAddrs ...string) *Resolver {
+	r := &Resolver{}
+	for _, addr := range dnsServerAddrs {
+		ns := nameServer{addr: addr}
+		ns.resolver = r.newNetResolver(ns.hostPort())
+		r.servers = append(r.servers, ns)
+	}
+	return r
+}
+
+// a resolver sending its queries to `hostPort`
+func (r *Resolver) newNetResolver(hostPort string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo:     true, // 'false' seems to result in using the default (network's) DNS server, avoiding lookups via the IP address provided
+		StrictErrors: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			// udp, or tcp when retrying a truncated response
+			recordServer(ctx, hostPort)
+			return r.dialer(network).DialContext(ctx, network, hostPort)
+		},
+	}
+}
+
+// Use the system's DNS configuration, as `net.DefaultResolver` does, but
+// recording which of its servers each lookup was sent to
+func NewSystemResolver() *Resolver {
+	r := &Resolver{}
+	r.servers = []nameServer{r.systemServer()}
+	return r
+}
+
+func (r *Resolver) systemServer() nameServer {
+	return nameServer{resolver: &net.Resolver{
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			recordServer(ctx, address)
+			return r.dialer(network).DialContext(ctx, network, address)
 		},
+	}}
+}
+
+// Use the `*net.Resolver` provided as-is; the caller is responsible for its
+// configuration (PreferGo, Dial, StrictErrors etc.)
+func NewResolverWith(resolver *net.Resolver) *Resolver {
+	return &Resolver{
+		servers: []nameServer{{resolver: resolver}},
+	}
+}
+
+// Resolve at most `n` hostnames at once; the cap is shared by concurrent
+// `ResolveHostnames` calls on this `Resolver`, and also bounds each
+// hostname's reverse lookups
+func (r *Resolver) SetConcurrency(n int) {
+	if n > 0 {
+		r.limit = make(chan struct{}, n)
+	}
+}
+
+// Call `hook` with each hostname's result as soon as it completes; hooks may
+// be called concurrently
+func (r *Resolver) OnResult(hook func(*ResolveResult)) {
+	r.resultHooks = append(r.resultHooks, hook)
+}
+
+// Disable all of the `Resolver`'s logging, including errors, for callers
+// using only the returned results; the messages aren't formatted, and the
+// per-hostname lines' arguments (address lists etc.) aren't built either
+func (r *Resolver) DisableLogging() {
+	r.noLog = true
+}
+
+// whether the info and debug messages are logged, to skip building their
+// arguments on the per-hostname path when they aren't
+func (r *Resolver) infoEnabled() bool {
+	return !r.quiet && !r.noLog
+}
+
+func (r *Resolver) logInfo(msg string, args ...interface{}) {
+	if r.infoEnabled() {
+		LogInfo(msg, args...)
+	}
+}
+
+func (r *Resolver) logDebug(msg string, args ...interface{}) {
+	if r.infoEnabled() {
+		LogDebug(msg, args...)
+	}
+}
+
+func (r *Resolver) logWarning(msg string, args ...interface{}) {
+	if !r.noLog {
+		LogWarning(msg, args...)
+	}
+}
+
+func (r *Resolver) logError(msg string, args ...interface{}) {
+	if !r.noLog {
+		LogError(msg, args...)
 	}
 }
 
 // Resolves the `hostname` provided for the `network` (ip4|ip6|ip) provided and resolves the reverse
-func (r *Resolver) ResolveHostname(ctx context.Context, network NetworkString, hostname string) {
+func (r *Resolver) ResolveHostname(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
 	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+	if r.tracer != nil {
+		var span trace.Span
+		ctx, span = r.tracer.Start(ctx, "ResolveHostname", trace.WithAttributes(attribute.String("hostname", hostname)))
+		defer func() {
+			endSpan(span, result.Err, attribute.String("server", result.Server), attribute.Int("addresses", len(result.IPs)))
+		}()
+	}
+
+	queryName := hostname
+	if host, port, ok := splitHostPort(hostname); ok {
+		queryName = host
+		result.QueryName = queryName
+		r.logInfo("Querying '%s' for hostname '%s' (ignoring port %s)\n", queryName, hostname, port)
+	}
+	if r.appendDomain != "" {
+		if appended := appendDomain(queryName, r.appendDomain); appended != queryName {
+			queryName = appended
+			result.QueryName = queryName
+			r.logInfo("Querying '%s' for hostname '%s'\n", queryName, hostname)
+		}
+	}
+
+	if err := validateHostname(queryName, r.allowWildcard); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if err := checkMixedScripts(queryName); err != nil && !matchesSuffix(queryName, r.idnAllow) {
+		if r.idnStrict {
+			r.logError("Not resolving: %s Error - '%s' (possible homograph)\n", hostname, err.Error())
+			result.Err = newResolveError(hostname, err)
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		r.logWarning("Suspicious hostname %s: %s (possible homograph)\n", hostname, err.Error())
+	}
+	r.explainf("Validating hostname '%s'... valid (labels of at most 63 and a name of at most 255 octets)", queryName)
+	if r.allowWildcard && strings.HasPrefix(queryName, "*.") {
+		probeName, err := wildcardProbeName(queryName)
+		if err != nil {
+			r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+			result.Err = newResolveError(hostname, err)
+			result.Duration = time.Since(startTime)
+			return result
+		}
+		queryName = probeName
+		result.QueryName = queryName
+		r.logInfo("Querying '%s' to test for a wildcard record for '%s'\n", queryName, hostname)
+	}
 
-	ips, err := r.resolver.LookupIP(ctx, string(network), hostname)
+	lookupCtx, answered := withServerRecorder(ctx)
+	ips, ns, err := r.lookupIP(lookupCtx, network, queryName)
+	if r.tryPartial(network, err) {
+		if familyIPs, familyNS, familyErr := r.lookupEachFamily(lookupCtx, queryName); len(familyIPs) > 0 {
+			r.logWarning("Partial result for %s: %s; counting it as resolved (-partial-ok)\n", hostname, familyErr.Error())
+			ips, ns, err = familyIPs, familyNS, nil
+			result.PartialErr = familyErr
+		}
+	}
+	if err != nil && r.fallbackSuffix != "" && r.isNXDomain(ctx, ns, queryName, err) {
+		fallbackName := appendDomain(queryName, r.fallbackSuffix)
+		if validErr := validateHostname(fallbackName, false); validErr != nil {
+			r.logWarning("Not querying '%s' for hostname '%s': Error - '%s'\n", fallbackName, hostname, validErr.Error())
+		} else if fallbackName != queryName {
+			r.logInfo("No such host '%s'; querying '%s' for hostname '%s'\n", queryName, fallbackName, hostname)
+			if fallbackIPs, fallbackNS, fallbackErr := r.lookupIP(lookupCtx, network, fallbackName); fallbackErr == nil {
+				r.logInfo("Resolved hostname '%s' as '%s'\n", hostname, fallbackName)
+				ips, ns, err = fallbackIPs, fallbackNS, nil
+				queryName = fallbackName
+				result.QueryName = queryName
+			}
+		}
+	}
+	result.Server = answered.serverOr(ns)
+	if err == nil && ns.addr != "" && !answered.recorded() {
+		r.warnDialerBypassed(ns)
+	}
 	if err != nil {
-		if dnsErr, ok := err.(*net.DNSError); ok {
-			LogError("Failed to resolve: %s: Error - '%s', was not found: %t\n", hostname, dnsErr.Err, dnsErr.IsNotFound)
+		if isServFail(err) {
+			r.logError("Failed to resolve: %s: SERVFAIL from %s (a problem with the server rather than the name)\n", hostname, result.Server)
+		} else if dnsErr, ok := err.(*net.DNSError); ok {
+			r.logError("Failed to resolve: %s: Error - '%s', was not found: %t\n", hostname, dnsErr.Err, dnsErr.IsNotFound)
 		} else {
-			LogError("Failed to resolve: %s Error - '%s'", hostname, err.Error())
+			r.logError("Failed to resolve: %s Error - '%s'", hostname, err.Error())
 		}
-		return
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
 	}
+	if len(ips) == 0 {
+		r.logError("Failed to resolve: %s Error - '%s'", hostname, ErrNoAddresses.Error())
+		result.Err = newResolveError(hostname, ErrNoAddresses)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	r.explainf("Got %d address(es) for %s via %s", len(ips), queryName, ns)
+	ips = r.filterFamily(network, hostname, ips)
+	if len(ips) == 0 {
+		r.logError("Failed to resolve: %s Error - '%s'", hostname, ErrNoAddresses.Error())
+		result.Err = newResolveError(hostname, ErrNoAddresses)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	if !r.rawIPv6 {
+		ips = unmapIPv4(ips)
+	}
+	if r.sortIPs {
+		sortIPs(ips)
+	}
+	if r.firstIP {
+		ips = ips[:1]
+	}
+	result.IPs = ips
 
-	LogInfo("IP addresses for hostname '%s': %v\n", hostname, addrString(ips))
+	if r.infoEnabled() {
+		r.logInfo("IP addresses for hostname '%s' via %s: %v\n", r.displayName(hostname), result.Server, r.limitAddrs(ips))
+	}
 
-	r.resolveReverse(ctx, ips, hostname)
+	if !r.firstIP {
+		result.Reverse, result.ReverseErrs = r.resolveReverse(ctx, ips, hostname)
+	}
 
-	durationMs := time.Since(startTime).Milliseconds()
-	LogInfo("Duration for resolving %s: %d ms\n", hostname, durationMs)
+	if r.audit {
+		r.auditCNAME(ctx, queryName)
+	}
+
+	result.Duration = time.Since(startTime)
+	r.explainf("Done with %s after %s", hostname, formatDuration(result.Duration))
+	if r.infoEnabled() {
+		r.logInfo("Duration for resolving %s: %s\n", r.displayName(hostname), formatDuration(result.Duration))
+	}
+	return result
 }
 
-func (r *Resolver) ResolveHostnames(ctx context.Context, network NetworkString, hostnames []string) {
+// Resolves each of the `hostnames` concurrently; results are returned in the order of `hostnames`
+func (r *Resolver) ResolveHostnames(ctx context.Context, network NetworkString, hostnames []string) []*ResolveResult {
+	if r.delay > 0 {
+		return r.resolveSerially(ctx, network, hostnames)
+	}
+
+	collector := NewResultCollector(len(hostnames))
+	waits := newDependencyWaits(r.dependencies, hostnames)
 	var wg sync.WaitGroup
-	for _, hostname := range hostnames {
+	for i, hostname := range hostnames {
 		wg.Add(1)
 		go func() {
-			r.ResolveHostname(ctx, network, hostname)
-			wg.Done()
+			defer wg.Done()
+			// waiting for the dependencies doesn't take up one of the `limit` slots
+			result := waits.await(r, hostname)
+			if result != nil {
+				r.runHooks(result)
+			} else {
+				if r.limit != nil {
+					r.limit <- struct{}{}
+				}
+				result = r.resolveChecked(ctx, network, hostname)
+				if r.limit != nil {
+					<-r.limit
+				}
+			}
+			waits.finish(i, result)
+			collector.Add(i, result)
 		}()
 	}
 	wg.Wait()
+	return collector.InOrder()
+}
+
+// resolve `hostname` in the configured mode, then run the checks and hooks on its result
+func (r *Resolver) resolveChecked(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+	if result := r.cachedResult(network, hostname); result != nil {
+		// the latency and TTL checks are about a lookup, so don't apply (see
+		// main); the others are about the answer, cached or not
+		if r.audit {
+			name := result.Hostname
+			if result.QueryName != "" {
+				name = result.QueryName
+			}
+			r.auditCNAME(ctx, name)
+		}
+		r.checkAnswer(ctx, result)
+		r.runHooks(result)
+		return result
+	}
+	result := r.resolveOne(ctx, network, hostname)
+	r.checkLatency(result)
+	r.checkTTL(ctx, network, result)
+	r.checkAnswer(ctx, result)
+	r.storeResult(network, result)
+	r.runHooks(result)
+	return result
 }
 
-// perform a reverse lookup for each ip address
-func (r *Resolver) resolveReverse(ctx context.Context, ips []net.IP, hostname string) {
+// the checks on a result's addresses and names, for looked up and cached
+// results alike
+func (r *Resolver) checkAnswer(ctx context.Context, result *ResolveResult) {
+	r.checkCNAMEs(ctx, result)
+	r.checkPort(ctx, result)
+	r.checkFCrDNS(ctx, result)
+}
+
+func (r *Resolver) runHooks(result *ResolveResult) {
+	for _, hook := range r.resultHooks {
+		hook(result)
+	}
+}
+
+// flag a resolution that took longer than the configured maximum latency
+func (r *Resolver) checkLatency(result *ResolveResult) {
+	if r.maxLatency <= 0 || result.Duration <= r.maxLatency {
+		return
+	}
+	result.LatencyErr = fmt.Errorf("%w: %s (max %s)", ErrLatencyExceeded, formatDuration(result.Duration), formatDuration(r.maxLatency))
+	if r.maxLatencyFatal {
+		r.logError("Resolving %s exceeded the maximum latency: %s (max %s)\n", result.Hostname, formatDuration(result.Duration), formatDuration(r.maxLatency))
+	} else {
+		r.logWarning("Resolving %s exceeded the maximum latency: %s (max %s)\n", result.Hostname, formatDuration(result.Duration), formatDuration(r.maxLatency))
+	}
+}
+
+// render `name` for output, consistently with or without the trailing dot:
+// reverse names come back fully qualified while hostnames usually aren't
+func (r *Resolver) displayName(name string) string {
+	if r.trailingDot == "keep" {
+		return dns.Fqdn(name)
+	}
+	return strings.TrimSuffix(name, ".")
+}
+
+// resolve `hostname` in the configured mode
+func (r *Resolver) resolveOne(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+	switch {
+	case r.recordType != nil && r.recordType.lookup != nil:
+		return r.recordType.lookup(r, ctx, network, hostname)
+	case r.axfr:
+		return r.TransferZone(ctx, hostname)
+	case r.spfExpand:
+		return r.ExpandSPF(ctx, network, hostname)
+	case r.noRecurse:
+		return r.ResolveNoRecurse(ctx, network, hostname)
+	case r.iterative:
+		return r.ResolveIterative(ctx, network, hostname)
+	case r.diffDefault:
+		return r.ResolveDiffDefault(ctx, network, hostname)
+	case r.mergeServers:
+		return r.ResolveMergeServers(ctx, network, hostname)
+	case r.cacheProbe:
+		return r.ResolveCacheProbe(ctx, network, hostname)
+	case r.compareTransport:
+		return r.ResolveCompareTransport(ctx, network, hostname)
+	case isReverseName(hostname):
+		return r.ResolveReverseName(ctx, hostname)
+	default:
+		return r.ResolveHostname(ctx, network, hostname)
+	}
+}
+
+// the most reverse lookups run at once for a single hostname
+const maxReverseWorkers = 8
+
+// the reverse lookups run at once for a single hostname: no more than
+// `-concurrency` (the hostname already holds one of its slots, so they can't
+// share them), up to `maxReverseWorkers`
+func (r *Resolver) reverseWorkers() int {
+	if r.limit != nil {
+		return min(cap(r.limit), maxReverseWorkers)
+	}
+	return maxReverseWorkers
+}
+
+// perform a reverse lookup for each ip address, returning the names found
+// (keyed by address) and the errors for the failed lookups
+func (r *Resolver) resolveReverse(ctx context.Context, ips []net.IP, hostname string) (map[string][]string, []error) {
 	blockedIpStr := "0.0.0.0"
+	reverse := map[string][]string{}
+	var errs []error
 
+	var targets []net.IP
 	for _, ip := range ips {
+		if !inFamily(ip, r.reverseFamily) {
+			r.explainf("Skipping the reverse lookup for %s, which isn't %s (-reverse-family)", ip, r.reverseFamily)
+			continue
+		}
+
 		// ignore blocked hostnames
 		if ip.Equal(net.ParseIP(blockedIpStr)) {
 			if len(ips) == 1 {
 				// we're done if this addr is the only IP addr.
-				LogInfo("Ignoring attempt to resolve reverse for %s as it previously resolved to %s", hostname, blockedIpStr)
-				return
+				r.logInfo("Ignoring attempt to resolve reverse for %s as it previously resolved to %s", hostname, blockedIpStr)
+				return nil, nil
 			} else {
 				// This is a remote possibility I suppose, but we'll handle it anyway in the rare event it occurs?
 				continue
 			}
 		}
+		targets = append(targets, ip)
+	}
 
-		names, err := r.resolver.LookupAddr(ctx, ip.String())
+	// the addresses' lookups are independent, so they run at once (a few at a
+	// time, see `reverseWorkers`); their outcomes, and the servers queried for
+	// `-explain`, are then logged in address order
+	lookupNames := make([][]string, len(targets))
+	lookupErrs := make([]error, len(targets))
+	queried := make([][]string, len(targets)) // nil when answered from the reverse cache
+	sem := make(chan struct{}, r.reverseWorkers())
+	var wg sync.WaitGroup
+	for i, ip := range targets {
+		lookup := func() (names []string, err error) {
+			ctx := ctx
+			if r.tracer != nil {
+				var span trace.Span
+				ctx, span = r.tracer.Start(ctx, "LookupAddr", trace.WithAttributes(attribute.String("ip", ip.String())))
+				defer func() { endSpan(span, err, attribute.Int("names", len(names))) }()
+			}
+			_, err = r.withFailover(ctx, func(ns nameServer) error {
+				var err error
+				queried[i] = append(queried[i], ns.String())
+				names, err = ns.resolver.LookupAddr(ctx, ip.String())
+				return err
+			})
+			return names, err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if r.reverseCache != nil {
+				lookupNames[i], lookupErrs[i] = r.reverseCache.lookup(ip.String(), lookup)
+			} else {
+				lookupNames[i], lookupErrs[i] = lookup()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, ip := range targets {
+		if r.reverseCache != nil {
+			r.explainf("Checking the reverse cache for %s (-cache-reverse); a cached answer isn't queried again", ip)
+			if queried[i] == nil {
+				r.explainf("Reverse lookup for %s answered from the reverse cache", ip)
+			}
+		}
+		for _, server := range queried[i] {
+			r.explainf("Reverse lookup for %s: querying PTR records for %s via %s...", ip, reverseQueryName(ip.String()), server)
+		}
+		names, err := lookupNames[i], lookupErrs[i]
 		if err != nil {
+			errs = append(errs, err)
 			if dnsErr, ok := err.(*net.DNSError); ok {
-				LogError("Error performing reverse lookup for %s (%s): Error - '%s', was not found: %t\n", hostname, ip.String(), dnsErr.Err, dnsErr.IsNotFound)
+				// a missing PTR record is common and usually harmless
+				if dnsErr.IsNotFound && !r.reverseErrorsFatal {
+					r.logWarning("No reverse for %s (%s): Error - '%s'\n", hostname, ip.String(), dnsErr.Err)
+				} else {
+					r.logError("Error performing reverse lookup for %s (%s): Error - '%s', was not found: %t\n", hostname, ip.String(), dnsErr.Err, dnsErr.IsNotFound)
+				}
+			} else {
+				r.logError("Error performing reverse lookup for %s (%s): Error - '%s'\n", hostname, ip.String(), err.Error())
 			}
 		} else {
-			LogInfo("Reverse for %s (%s): %v", ip, hostname, strings.Join(names, ", "))
+			names, suppressed := filterReverseNames(names, r.reverseIgnore)
+			for i, name := range names {
+				names[i] = r.displayName(name)
+			}
+			if len(names) > 0 {
+				reverse[ip.String()] = names
+				if r.infoEnabled() {
+					r.logInfo("Reverse for %s (%s): %v", ip, r.displayName(hostname), r.limitNames(names))
+				}
+			}
+			if suppressed > 0 {
+				r.logInfo("Suppressed %d reverse name(s) for %s (%s) matching -reverse-ignore-suffix\n", suppressed, ip, hostname)
+			}
+		}
+	}
+	return reverse, errs
+}
+
+// whether `ip` belongs to the `family` (ip4|ip6); `ip` or unset matches either
+func inFamily(ip net.IP, family NetworkString) bool {
+	switch family {
+	case IPv4:
+		return ip.To4() != nil
+	case IPv6:
+		return ip.To4() == nil
+	default:
+		return true
+	}
+}
+
+// Drop the addresses not of the queried `network`'s family, which a
+// misbehaving resolver could return, so the output and reverse lookups only
+// see what was asked for. Both the Go resolver and the lower-level client
+// return A records' addresses in their 4-byte form and AAAA records' in their
+// 16-byte form. An IPv4-mapped IPv6 address (`::ffff:1.2.3.4`, from an AAAA
+// record) belongs to neither family alone, so is dropped with ip4 and ip6
+// alike, as the Go resolver does for ip6, and only kept with ip (shown mapped
+// with `-raw-ipv6`)
+func (r *Resolver) filterFamily(network NetworkString, hostname string, ips []net.IP) []net.IP {
+	if network != IPv4 && network != IPv6 {
+		return ips
+	}
+	kept := ips[:0:0]
+	for _, ip := range ips {
+		if (network == IPv4 && len(ip) == net.IPv4len) || (network == IPv6 && ip.To4() == nil) {
+			kept = append(kept, ip)
+		} else {
+			r.logDebug("Discarding %s for %s: not an %s address\n", ip, r.displayName(hostname), network)
+		}
+	}
+	return kept
+}
+
+// drop the reverse `names` under any of the `ignore` suffixes, returning the remainder and how many were dropped
+func filterReverseNames(names []string, ignore []string) ([]string, int) {
+	if len(ignore) == 0 {
+		return names, 0
+	}
+	kept := make([]string, 0, len(names))
+	for _, name := range names {
+		if !matchesSuffix(name, ignore) {
+			kept = append(kept, name)
+		}
+	}
+	return kept, len(names) - len(kept)
+}
+
+// sort `ips` in place, IPv4 addresses first and then numerically, so their
+// order doesn't depend on the resolver's (which isn't stable)
+func sortIPs(ips []net.IP) {
+	sort.SliceStable(ips, func(i, j int) bool {
+		return compareIPs(ips[i], ips[j]) < 0
+	})
+}
+
+// the `sortIPs` order of `a` and `b`: negative when `a` comes first
+func compareIPs(a, b net.IP) int {
+	av4, bv4 := a.To4() != nil, b.To4() != nil
+	if av4 != bv4 {
+		if av4 {
+			return -1
+		}
+		return 1
+	}
+	return bytes.Compare(a.To16(), b.To16())
+}
+
+// convert IPv4-mapped IPv6 addresses (::ffff:1.2.3.4) to their 4-byte form
+func unmapIPv4(ips []net.IP) []net.IP {
+	unmapped := make([]net.IP, len(ips))
+	for i, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			unmapped[i] = ip4
+		} else {
+			unmapped[i] = ip
 		}
 	}
+	return unmapped
+}
+
+// `net.IP.String` always renders IPv4-mapped addresses as dotted-quad;
+// show the mapped form for any 16-byte address that hasn't been unmapped
+func ipString(ip net.IP) string {
+	if len(ip) == net.IPv6len && ip.To4() != nil {
+		return "::ffff:" + ip.To4().String()
+	}
+	return ip.String()
 }
 
 func addrString(ips []net.IP) string {
 	addrStr := ""
 	for i, ip := range ips {
 		if i == len(ips)-1 {
-			addrStr += ip.String() // avoid appending comma to last token
+			addrStr += ipString(ip) // avoid appending comma to last token
 		} else {
-			addrStr += ip.String() + ", "
+			addrStr += ipString(ip) + ", "
 		}
 	}
 	return addrStr
 }
+
+// `addrString` for the human output, showing only the first `-answer-limit`
+// addresses, so a huge list doesn't flood the terminal
+func (r *Resolver) limitAddrs(ips []net.IP) string {
+	if r.answerLimit > 0 && len(ips) > r.answerLimit {
+		return fmt.Sprintf("%s (... and %d more)", addrString(ips[:r.answerLimit]), len(ips)-r.answerLimit)
+	}
+	return addrString(ips)
+}
+
+// the reverse `names` for the human output, limited as by `limitAddrs`
+func (r *Resolver) limitNames(names []string) string {
+	if r.answerLimit > 0 && len(names) > r.answerLimit {
+		return fmt.Sprintf("%s (... and %d more)", strings.Join(names[:r.answerLimit], ", "), len(names)-r.answerLimit)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrailingDot(t *testing.T) {
+	ts := newTestServer(t)
+	tests := []struct {
+		trailingDot string
+		want        string
+	}{
+		{"strip", "ok.test: [192.0.2.1] (ok.test)"},
+		{"keep", "ok.test.: [192.0.2.1] (ok.test.)"},
+	}
+	for _, tt := range tests {
+		for _, hostname := range []string{"ok.test", "ok.test."} {
+			t.Run(tt.trailingDot+"/"+hostname, func(t *testing.T) {
+				r := newTestResolver(t, ts, nil)
+				r.noLog = false
+				r.trailingDot = tt.trailingDot
+				stdout, _ := captureLogs(t)
+
+				result := r.ResolveHostname(testContext(t), IPv4, hostname)
+				if result.Err != nil {
+					t.Fatal(result.Err)
+				}
+				if got := r.compactLine(result); !strings.HasPrefix(got, tt.want+" ") {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+				// the forward and reverse names are logged alike
+				name := strings.SplitN(tt.want, ":", 2)[0]
+				for _, line := range []string{
+					"IP addresses for hostname '" + name + "' via",
+					"Duration for resolving " + name + ":",
+					"Reverse for 192.0.2.1 (" + name + "): " + name + "\n",
+				} {
+					if !strings.Contains(stdout.String(), line) {
+						t.Errorf("log %q doesn't contain %q", stdout.String(), line)
+					}
+				}
+			})
+		}
+	}
+}
+
+// the cost of the logging on the per-hostname path, with the log lines
+// discarded, against none at all
+func BenchmarkDisableLogging(b *testing.B) {
+	ts := newTestServer(b)
+	captureLogs(b)
+	for _, disabled := range []bool{false, true} {
+		name := "logging"
+		if disabled {
+			name = "DisableLogging"
+		}
+		b.Run(name, func(b *testing.B) {
+			r := newTestResolver(b, ts, nil)
+			r.noLog = disabled
+			b.ReportAllocs()
+			for b.Loop() {
+				r.ResolveHostname(b.Context(), IPv4, "multi.test")
+			}
+		})
+	}
+}
+
+func TestFallbackSuffix(t *testing.T) {
+	ts := newTestServer(t)
+	long := nameOfLabels(63, 63, 63, 50)
+	tests := []struct {
+		hostname  string
+		queryName string // empty when the fallback isn't used
+		resolved  bool
+	}{
+		{"www", "www.fallback.test", true},
+		// an empty answer isn't NXDOMAIN, so doesn't fall back
+		{"nodata.test", "", false},
+		// too long with the suffix appended
+		{long, "", false},
+	}
+	for _, raw := range []bool{false, true} {
+		for _, tt := range tests {
+			r := newTestResolver(t, ts, nil)
+			r.fallbackSuffix = "fallback.test"
+			r.use0x20 = raw
+			result := r.ResolveHostname(testContext(t), IPv4, tt.hostname)
+			if (result.Err == nil) != tt.resolved {
+				t.Errorf("raw %t, %s: got error %v, want resolved %t", raw, tt.hostname, result.Err, tt.resolved)
+			}
+			if result.QueryName != tt.queryName {
+				t.Errorf("raw %t, %s: queried %q, want %q", raw, tt.hostname, result.QueryName, tt.queryName)
+			}
+		}
+	}
+}
+
+func TestFilterFamily(t *testing.T) {
+	v4 := net.ParseIP("192.0.2.1").To4()
+	v6 := net.ParseIP("2001:db8::1")
+	mapped := net.ParseIP("::ffff:192.0.2.2") // 16 bytes, as from an AAAA record
+	r := &Resolver{noLog: true}
+	tests := []struct {
+		network NetworkString
+		want    string
+	}{
+		{IPv4, "192.0.2.1"},
+		{IPv6, "2001:db8::1"},
+		{"ip", "192.0.2.1, 2001:db8::1, ::ffff:192.0.2.2"},
+	}
+	for _, tt := range tests {
+		got := r.filterFamily(tt.network, "mixed.test", []net.IP{v4, v6, mapped})
+		if addrs := rawAddrString(got); addrs != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.network, addrs, tt.want)
+		}
+	}
+}
+
+// a server answering with both families whatever was asked, via both the Go
+// resolver (which drops the records of the other type itself) and the
+// lower-level client
+func TestWrongFamily(t *testing.T) {
+	ts := newTestServer(t)
+	tests := []struct {
+		network NetworkString
+		rawIPv6 bool
+		want    string
+	}{
+		{IPv4, false, "192.0.2.40"},
+		{IPv4, true, "192.0.2.40"},
+		{IPv6, false, "2001:db8::40"},
+		{IPv6, true, "2001:db8::40"},
+	}
+	for _, raw := range []bool{false, true} {
+		for _, tt := range tests {
+			r := newTestResolver(t, ts, nil)
+			r.use0x20 = raw
+			r.rawIPv6 = tt.rawIPv6
+			result := r.ResolveHostname(testContext(t), tt.network, "anyfamily.test")
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			if got := rawAddrString(result.IPs); got != tt.want {
+				t.Errorf("raw %t, %s, raw-ipv6 %t: got %s, want %s", raw, tt.network, tt.rawIPv6, got, tt.want)
+			}
+		}
+	}
+}
+
+// `ips` without unmapping the IPv4-mapped ones, as `ipString` does
+func rawAddrString(ips []net.IP) string {
+	strs := make([]string, len(ips))
+	for i, ip := range ips {
+		strs[i] = ip.String()
+		if len(ip) == net.IPv6len && ip.To4() != nil {
+			strs[i] = "::ffff:" + ip.To4().String()
+		}
+	}
+	return strings.Join(strs, ", ")
+}
+
+func TestReverseConcurrency(t *testing.T) {
+	var ips []net.IP
+	for i := 1; i <= 12; i++ {
+		ips = append(ips, net.IPv4(192, 0, 2, byte(i)))
+	}
+	tests := []struct {
+		concurrency int
+		want        int
+	}{
+		{0, maxReverseWorkers},
+		{2, 2},
+		{100, maxReverseWorkers},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.concurrency), func(t *testing.T) {
+			ts := newTestServer(t)
+			ts.delay.Store(int64(50 * time.Millisecond))
+			r := newTestResolver(t, ts, nil)
+			r.SetConcurrency(tt.concurrency)
+			r.resolveReverse(testContext(t), ips, "many.test")
+			if got := ts.maxInFlight.Load(); got > int64(tt.want) {
+				t.Errorf("%d reverse lookups at once, want at most %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReverseExplainOrder(t *testing.T) {
+	ts := newTestServer(t)
+	ts.delay.Store(int64(10 * time.Millisecond))
+	r := newTestResolver(t, ts, nil)
+	r.noLog = false
+	r.explain = true
+	stdout, _ := captureLogs(t)
+
+	ips := []net.IP{net.ParseIP("192.0.2.4"), net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.9")}
+	r.resolveReverse(testContext(t), ips, "many.test")
+
+	var order []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if _, after, ok := strings.Cut(line, "EXPLAIN: Reverse lookup for "); ok {
+			order = append(order, strings.Fields(after)[0])
+		}
+	}
+	want := "192.0.2.4: 192.0.2.1: 192.0.2.9:"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("explained in the order %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the `-cache-file` couldn't be parsed, so it's discarded
+var errCorruptCache = errors.New("corrupt cache file")
+
+// a successful forward lookup, served from the cache until it expires
+type cachedResult struct {
+	QueryName string              `json:"query_name,omitempty"` // when it differs from the hostname
+	IPs       []string            `json:"ips"`
+	Reverse   map[string][]string `json:"reverse,omitempty"`
+	Server    string              `json:"server"`
+	TTL       int64               `json:"ttl"` // seconds
+	Expires   time.Time           `json:"expires"`
+}
+
+// Persists the successful forward lookups between runs (`-cache-file`), each
+// until the lowest TTL of its answer records expires, so repeated runs over
+// the same hostnames don't query them again. Keyed by the network type, the
+// hostname and the lookup's scope (see `resultCacheScope`), as the addresses
+// differ by family, by server and with some options
+type resultCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResult
+	hits    int
+}
+
+// a missing cache file is an empty cache (first run); a corrupt one is too,
+// along with an `errCorruptCache` error
+func loadResultCache(path string) (*resultCache, error) {
+	c := &resultCache{entries: map[string]cachedResult{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		c.entries = map[string]cachedResult{}
+		return c, fmt.Errorf("%w: %w", errCorruptCache, err)
+	}
+	return c, nil
+}
+
+// write the unexpired entries to `path`
+func (c *resultCache) save(path string, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if !now.Before(entry.Expires) {
+			delete(c.entries, key)
+		}
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func resultCacheKey(network NetworkString, hostname, scope string) string {
+	return string(network) + " " + strings.ToLower(strings.TrimSuffix(hostname, ".")) + " " + scope
+}
+
+// What a cached answer depends on besides the network type and the hostname:
+// the servers queried (in any order, as `-shuffle-servers` reorders them) and
+// the options changing the addresses or the reverse names found (or their
+// order or form), so a run with other ones doesn't serve this run's answers
+func (r *Resolver) resultCacheScope() string {
+	servers := make([]string, len(r.servers))
+	for i, ns := range r.servers {
+		servers[i] = "default"
+		if ns.addr != "" {
+			servers[i] = ns.hostPort()
+		}
+	}
+	slices.Sort(servers)
+	scope := []string{"servers=" + strings.Join(servers, ",")}
+	if r.ecs != nil {
+		scope = append(scope, "ecs="+r.ecs.String())
+	}
+	if r.appendDomain != "" {
+		scope = append(scope, "append-domain="+r.appendDomain)
+	}
+	if r.fallbackSuffix != "" {
+		scope = append(scope, "fallback-suffix="+r.fallbackSuffix)
+	}
+	if r.partialOK {
+		scope = append(scope, "partial-ok")
+	}
+	if r.firstIP {
+		scope = append(scope, "first-ip")
+	}
+	if r.reverseFamily != "" {
+		scope = append(scope, "reverse-family="+string(r.reverseFamily))
+	}
+	if len(r.reverseIgnore) > 0 {
+		scope = append(scope, "reverse-ignore="+strings.Join(r.reverseIgnore, ","))
+	}
+	if r.sortIPs {
+		scope = append(scope, "sort-ips")
+	}
+	if r.trailingDot == "keep" {
+		scope = append(scope, "trailing-dot=keep")
+	}
+	return strings.Join(scope, " ")
+}
+
+// the unexpired entry for `key`, if any
+func (c *resultCache) lookup(key string, now time.Time) (cachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.Expires) {
+		return cachedResult{}, false
+	}
+	c.hits++
+	return entry, true
+}
+
+// cache `result` under `key` when it succeeded with a known, non-zero TTL
+func (c *resultCache) store(key string, result *ResolveResult, now time.Time) {
+	if result.Err != nil || !result.HasTTL || result.TTL <= 0 || len(result.IPs) == 0 {
+		return
+	}
+	entry := cachedResult{QueryName: result.QueryName, Reverse: result.Reverse, Server: result.Server, TTL: int64(result.TTL.Seconds()), Expires: now.Add(result.TTL)}
+	for _, ip := range result.IPs {
+		entry.IPs = append(entry.IPs, ip.String())
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+}
+
+// the number of hostnames served from the cache so far
+func (c *resultCache) hitCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits
+}
+
+// Cache `result` in the `-cache-file`, if set
+func (r *Resolver) storeResult(network NetworkString, result *ResolveResult) {
+	if r.resultCache == nil {
+		return
+	}
+	r.resultCache.store(resultCacheKey(network, result.Hostname, r.resultCacheScope()), result, time.Now())
+}
+
+// The result for `hostname` from the `-cache-file`, logged as a lookup would
+// be; nil when it isn't cached (or has expired). Its TTL is what remains of
+// the stored one, as a caching resolver would answer
+func (r *Resolver) cachedResult(network NetworkString, hostname string) *ResolveResult {
+	if r.resultCache == nil {
+		return nil
+	}
+	now := time.Now()
+	entry, ok := r.resultCache.lookup(resultCacheKey(network, hostname, r.resultCacheScope()), now)
+	if !ok {
+		return nil
+	}
+
+	remaining := entry.Expires.Sub(now).Truncate(time.Second)
+	result := &ResolveResult{Hostname: hostname, QueryName: entry.QueryName, Server: entry.Server, Reverse: entry.Reverse, TTL: remaining, HasTTL: true}
+	for _, s := range entry.IPs {
+		if ip := net.ParseIP(s); ip != nil {
+			result.IPs = append(result.IPs, ip)
+		}
+	}
+	if !r.rawIPv6 {
+		result.IPs = unmapIPv4(result.IPs)
+	}
+	r.logInfo("IP addresses for hostname '%s' from the cache (via %s, expires in %s): %v\n", r.displayName(hostname), entry.Server, remaining, r.limitAddrs(result.IPs))
+	for _, ip := range result.IPs {
+		if names := entry.Reverse[ip.String()]; len(names) > 0 {
+			r.logInfo("Reverse for %s (%s): %v (cached)\n", ip, r.displayName(hostname), r.limitNames(names))
+		}
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultCacheScope(t *testing.T) {
+	newResolver := func(addrs ...string) *Resolver {
+		r := &Resolver{noLog: true}
+		for _, addr := range addrs {
+			r.servers = append(r.servers, nameServer{addr: addr})
+		}
+		return r
+	}
+	stored := newResolver("192.0.2.53", "192.0.2.54")
+	stored.resultCache = &resultCache{entries: map[string]cachedResult{}}
+	stored.storeResult(IPv4, &ResolveResult{Hostname: "ok.test", IPs: []net.IP{net.ParseIP("192.0.2.1")}, TTL: time.Minute, HasTTL: true})
+
+	_, subnet, _ := net.ParseCIDR("198.51.100.0/24")
+	servers := []string{"192.0.2.53", "192.0.2.54"}
+	tests := []struct {
+		name      string
+		servers   []string
+		network   NetworkString
+		configure func(r *Resolver)
+		hit       bool
+	}{
+		{name: "same", servers: servers, network: IPv4, hit: true},
+		{name: "servers reordered", servers: []string{"192.0.2.54", "192.0.2.53"}, network: IPv4, hit: true},
+		{name: "other servers", servers: []string{"192.0.2.99"}, network: IPv4},
+		{name: "default resolver", servers: []string{""}, network: IPv4},
+		{name: "other family", servers: servers, network: IPv6},
+		{name: "ecs", servers: servers, network: IPv4, configure: func(r *Resolver) { r.ecs = subnet }},
+		{name: "append domain", servers: servers, network: IPv4, configure: func(r *Resolver) { r.appendDomain = "corp.test" }},
+		{name: "fallback suffix", servers: servers, network: IPv4, configure: func(r *Resolver) { r.fallbackSuffix = "corp.test" }},
+		{name: "sorted", servers: servers, network: IPv4, configure: func(r *Resolver) { r.sortIPs = true }},
+		{name: "trailing dot", servers: servers, network: IPv4, configure: func(r *Resolver) { r.trailingDot = "keep" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newResolver(tt.servers...)
+			if tt.configure != nil {
+				tt.configure(r)
+			}
+			r.resultCache = stored.resultCache
+			if hit := r.cachedResult(tt.network, "ok.test") != nil; hit != tt.hit {
+				t.Errorf("got hit=%t, want %t (scope %q)", hit, tt.hit, r.resultCacheScope())
+			}
+		})
+	}
+}
+
+func TestResultCacheRemainingTTL(t *testing.T) {
+	r := &Resolver{noLog: true, servers: []nameServer{{addr: "192.0.2.53"}}}
+	r.resultCache = &resultCache{entries: map[string]cachedResult{}}
+	key := resultCacheKey(IPv4, "ok.test", r.resultCacheScope())
+	r.resultCache.store(key, &ResolveResult{Hostname: "ok.test", IPs: []net.IP{net.ParseIP("192.0.2.1")}, TTL: 300 * time.Second, HasTTL: true}, time.Now().Add(-100*time.Second))
+
+	result := r.cachedResult(IPv4, "ok.test")
+	if result == nil {
+		t.Fatal("got no cached result")
+	}
+	if !result.HasTTL || result.TTL < 199*time.Second || result.TTL > 200*time.Second {
+		t.Errorf("got TTL %s, want the 200s remaining", result.TTL)
+	}
+}
+
+func TestResultCacheSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.json")
+	now := time.Now()
+	c := &resultCache{entries: map[string]cachedResult{}}
+	c.store("ip4 fresh.test", &ResolveResult{Hostname: "fresh.test", IPs: []net.IP{net.ParseIP("192.0.2.1")}, TTL: time.Hour, HasTTL: true}, now)
+	c.store("ip4 expired.test", &ResolveResult{Hostname: "expired.test", IPs: []net.IP{net.ParseIP("192.0.2.2")}, TTL: time.Minute, HasTTL: true}, now.Add(-time.Hour))
+	if err := c.save(path, now); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadResultCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.lookup("ip4 fresh.test", now); !ok {
+		t.Error("the unexpired entry wasn't saved")
+	}
+	if _, ok := loaded.entries["ip4 expired.test"]; ok {
+		t.Error("the expired entry was saved")
+	}
+	// written via a temporary file, renamed into place
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("got %d files in the directory, want only the cache file", len(files))
+	}
+}
+
+// the checks of the answer apply to a cached hostname too
+func TestResultCacheChecks(t *testing.T) {
+	ts := newTestServer(t)
+	r := newTestResolver(t, ts, nil)
+	r.resultCache = &resultCache{entries: map[string]cachedResult{}}
+	r.storeResult(IPv4, &ResolveResult{Hostname: "ok.test", IPs: []net.IP{net.ParseIP("127.0.0.1")}, Server: "192.0.2.53", TTL: time.Minute, HasTTL: true})
+
+	// a closed port on the cached address
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	r.checkPortNum = port
+	r.checkPortTimeout = time.Second
+	r.queryCNAME = true
+
+	queries := ts.queries.Load()
+	result := r.resolveChecked(testContext(t), IPv4, "ok.test")
+	if result.Server != "192.0.2.53" || result.Err != nil {
+		t.Fatalf("got %+v, want the cached result", result)
+	}
+	if result.PortErrs["127.0.0.1"] == nil {
+		t.Errorf("the port wasn't checked for the cached address: %v", result.PortErrs)
+	}
+	// only the CNAME chain was queried, not the addresses
+	if got := ts.queries.Load() - queries; got != 1 {
+		t.Errorf("the server got %d queries, want 1 for the CNAME chain", got)
+	}
+}
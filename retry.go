@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// upper bound for the delay before a retry, however many attempts were made
+const maxRetryBackoff = 5 * time.Second
+
+// upper bound for an escalating attempt's timeout (see `attemptContext`)
+const maxAttemptTimeout = 10 * time.Second
+
+// Randomized ("full jitter") exponential backoff between retries, so
+// hostnames retrying at once spread out rather than hitting the server in step
+type retryBackoff struct {
+	base time.Duration // the delay bound before the first retry, doubled for each further one
+	mu   sync.Mutex
+	rng  *rand.Rand
+}
+
+// Wait a random delay up to `base * 2^attempt` (at most `maxRetryBackoff`)
+// before retrying with the same random source for reproducible runs
+func (r *Resolver) SetRetryBackoff(base time.Duration, seed int64) {
+	r.backoff = retryBackoff{base: base, rng: rand.New(rand.NewSource(seed))}
+}
+
+// the jittered delay before the retry following `attempt` (0 for the first)
+func (b *retryBackoff) delay(attempt int) time.Duration {
+	if b.base <= 0 || b.rng == nil {
+		return 0
+	}
+	bound := maxRetryBackoff
+	// compare before shifting, as the shift can overflow for a large base
+	if b.base <= maxRetryBackoff>>attempt {
+		bound = b.base << attempt
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Duration(b.rng.Int63n(int64(bound) + 1))
+}
+
+// The context for the forward lookup's attempt number `attempt` (0 for the
+// first): with `attemptTimeout` set, the first attempt times out after it and
+// each retry after twice the previous one (at most `maxAttemptTimeout`); the
+// run's timeout or deadline still applies, so it cuts an attempt short
+func (r *Resolver) attemptContext(ctx context.Context, attempt int) (context.Context, context.CancelFunc) {
+	if r.attemptTimeout <= 0 {
+		return ctx, func() {}
+	}
+	timeout := maxAttemptTimeout
+	if r.attemptTimeout <= maxAttemptTimeout>>attempt {
+		timeout = r.attemptTimeout << attempt
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// whether a forward lookup outcome is worth another attempt: transient
+// failures always are, empty answers only with `retryOnEmpty`
+func (r *Resolver) retryable(ips []net.IP, err error) bool {
+	if err == nil {
+		return r.retryOnEmpty && len(ips) == 0
+	}
+	// the Go resolver reports an empty (NODATA) answer as not found, so
+	// it can't be told apart from NXDOMAIN here
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return r.retryOnEmpty
+	}
+	return true
+}
+
+// Forward lookup of `name`, failing over between servers and retrying up to
+// `retries` times; stops early once the context is done. Returns the server
+// used for the final attempt
+func (r *Resolver) lookupIP(ctx context.Context, network NetworkString, name string) ([]net.IP, nameServer, error) {
+	var ips []net.IP
+	var ns nameServer
+	var err error
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := r.attemptContext(ctx, attempt)
+		ns, err = r.withFailover(attemptCtx, func(ns nameServer) error {
+			var err error
+			r.explainf("Querying %s records for %s via %s...", queryTypeNames(network), name, ns)
+			if r.rawForward() {
+				ips, err = r.rawLookupIP(attemptCtx, ns, network, name)
+			} else {
+				ips, err = ns.resolver.LookupIP(attemptCtx, string(network), name)
+			}
+			if err != nil {
+				r.explainf("Query for %s via %s failed: '%s'", name, ns, shortError(err))
+			}
+			return err
+		})
+		cancel()
+		if attempt >= r.retries || ctx.Err() != nil || !r.retryable(ips, err) {
+			return ips, ns, err
+		}
+		delay := r.backoff.delay(attempt)
+		if err != nil {
+			r.logWarning("Retrying %s (attempt %d of %d) in %s after error: '%s'\n", name, attempt+1, r.retries, formatDuration(delay), err.Error())
+		} else {
+			r.logWarning("Retrying %s (attempt %d of %d) in %s after an empty answer\n", name, attempt+1, r.retries, formatDuration(delay))
+		}
+		select {
+		case <-ctx.Done():
+			return ips, ns, err
+		case <-time.After(delay):
+		}
+	}
+}
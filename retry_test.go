@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoffDelay(t *testing.T) {
+	bases := []time.Duration{time.Nanosecond, time.Millisecond, 100 * time.Millisecond, maxRetryBackoff, time.Hour, math.MaxInt64 / 2, math.MaxInt64}
+	for _, base := range bases {
+		r := &Resolver{}
+		r.SetRetryBackoff(base, 1)
+		b := &r.backoff
+		for attempt := 0; attempt < 70; attempt++ {
+			bound := maxRetryBackoff
+			if attempt < 62 && float64(base)*math.Pow(2, float64(attempt)) < float64(maxRetryBackoff) {
+				bound = base << attempt
+			}
+			for i := 0; i < 20; i++ {
+				if delay := b.delay(attempt); delay < 0 || delay > bound {
+					t.Fatalf("base %s, attempt %d: delay %s outside [0, %s]", base, attempt, delay, bound)
+				}
+			}
+		}
+	}
+
+	var unset retryBackoff
+	if delay := unset.delay(3); delay != 0 {
+		t.Errorf("got %s without a backoff, want 0", delay)
+	}
+}
+
+func TestAttemptContext(t *testing.T) {
+	for _, timeout := range []time.Duration{time.Millisecond, time.Second, maxAttemptTimeout, time.Hour, math.MaxInt64} {
+		r := &Resolver{attemptTimeout: timeout}
+		for attempt := 0; attempt < 70; attempt++ {
+			ctx, cancel := r.attemptContext(context.Background(), attempt)
+			deadline, ok := ctx.Deadline()
+			cancel()
+			if !ok {
+				t.Fatalf("timeout %s, attempt %d: no deadline", timeout, attempt)
+			}
+			if remaining := time.Until(deadline); remaining <= 0 || remaining > maxAttemptTimeout {
+				t.Fatalf("timeout %s, attempt %d: deadline in %s, want within (0, %s]", timeout, attempt, remaining, maxAttemptTimeout)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// Memoizes reverse lookups by IP for the duration of the process
+// (`-cache-reverse`), so hostnames sharing an address (e.g. behind a CDN)
+// don't repeat the same PTR query. Concurrent lookups of the same address
+// wait for the first rather than querying again
+type reverseCache struct {
+	mu      sync.Mutex
+	entries map[string]*reverseEntry
+}
+
+type reverseEntry struct {
+	done  chan struct{} // closed once `names` and `err` are set
+	names []string
+	err   error
+}
+
+func newReverseCache() *reverseCache {
+	return &reverseCache{entries: map[string]*reverseEntry{}}
+}
+
+// the reverse names for `ip`, from the cache or else via `lookup`; only
+// answers and missing PTR records are kept, so other errors are retried by
+// later lookups
+func (c *reverseCache) lookup(ip string, lookup func() ([]string, error)) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[ip]
+	if !ok {
+		entry = &reverseEntry{done: make(chan struct{})}
+		c.entries[ip] = entry
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		entry.names, entry.err = lookup()
+		var dnsErr *net.DNSError
+		if entry.err != nil && !(errors.As(entry.err, &dnsErr) && dnsErr.IsNotFound) {
+			c.mu.Lock()
+			delete(c.entries, ip)
+			c.mu.Unlock()
+		}
+		close(entry.done)
+	} else {
+		<-entry.done
+	}
+
+	// callers rewrite the names in place
+	return append([]string(nil), entry.names...), entry.err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	ipv4ReverseSuffix = ".in-addr.arpa"
+	ipv6ReverseSuffix = ".ip6.arpa"
+
+	// addresses a `-reverse-cidr` block may expand to
+	maxReverseCIDRSize = 65536
+)
+
+// whether `name` is in a reverse zone, to be queried for its PTR records
+func isReverseName(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	return strings.HasSuffix(name, ipv4ReverseSuffix) || strings.HasSuffix(name, ipv6ReverseSuffix)
+}
+
+// the address a full reverse name (`4.3.2.1.in-addr.arpa`, or the 32 nibbles
+// of an `ip6.arpa` name) stands for
+func ipFromReverseName(name string) (net.IP, error) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if strings.HasSuffix(name, ipv4ReverseSuffix) {
+		labels := strings.Split(strings.TrimSuffix(name, ipv4ReverseSuffix), ".")
+		if len(labels) != net.IPv4len {
+			return nil, fmt.Errorf("%w: '%s' isn't a full IPv4 reverse name", ErrInvalidHostname, name)
+		}
+		ip := make(net.IP, net.IPv4len)
+		for i, label := range labels {
+			octet, err := strconv.ParseUint(label, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad octet '%s' in '%s'", ErrInvalidHostname, label, name)
+			}
+			ip[net.IPv4len-1-i] = byte(octet)
+		}
+		return ip, nil
+	}
+
+	nibbles := strings.Split(strings.TrimSuffix(name, ipv6ReverseSuffix), ".")
+	if len(nibbles) != 2*net.IPv6len {
+		return nil, fmt.Errorf("%w: '%s' isn't a full IPv6 reverse name", ErrInvalidHostname, name)
+	}
+	ip := make(net.IP, net.IPv6len)
+	for i, nibble := range nibbles {
+		value, err := strconv.ParseUint(nibble, 16, 4)
+		if err != nil || len(nibble) != 1 {
+			return nil, fmt.Errorf("%w: bad nibble '%s' in '%s'", ErrInvalidHostname, nibble, name)
+		}
+		// the least significant nibble comes first
+		pos := len(nibbles) - 1 - i
+		ip[pos/2] |= byte(value) << (4 * (1 - pos%2))
+	}
+	return ip, nil
+}
+
+// Look up the PTR records for a reverse name passed directly, e.g.
+// `4.3.2.1.in-addr.arpa`; unlike the reverse lookups following a forward
+// one, a missing PTR record fails the result
+func (r *Resolver) ResolveReverseName(ctx context.Context, name string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: name}
+
+	ip, err := ipFromReverseName(name)
+	if err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", name, err.Error())
+		result.Err = newResolveError(name, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	result.IPs = []net.IP{ip}
+	result.Reverse, result.ReverseErrs = r.resolveReverse(ctx, result.IPs, name)
+	if len(result.ReverseErrs) > 0 {
+		result.Err = newResolveError(name, result.ReverseErrs[0])
+	}
+	result.Duration = time.Since(startTime)
+	return result
+}
+
+// the reverse names for every address in `cidr`, for a reverse DNS audit of
+// a network block; blocks of more than 65536 addresses are refused
+func reverseNamesForCIDR(cidr string) ([]string, error) {
+	ip, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones > 16 {
+		return nil, fmt.Errorf("'%s' has more than %d addresses", cidr, maxReverseCIDRSize)
+	}
+	if ip.To4() != nil {
+		ip = ip.To4()
+	}
+
+	var names []string
+	for addr := ip.Mask(ipNet.Mask); ipNet.Contains(addr); addr = nextIP(addr) {
+		name, err := dns.ReverseAddr(addr.String())
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, strings.TrimSuffix(name, "."))
+		if addr.Equal(lastIP(ipNet)) {
+			break
+		}
+	}
+	return names, nil
+}
+
+// the address following `ip`
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// the last address in `ipNet`
+func lastIP(ipNet *net.IPNet) net.IP {
+	last := make(net.IP, len(ipNet.IP))
+	for i := range ipNet.IP {
+		last[i] = ipNet.IP[i] | ^ipNet.Mask[i]
+	}
+	return last
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Records the round-trip time of a lookup's last query via the lower-level
+// client, carried by the lookup's context as for `serverRecorder`
+type rttRecorder struct {
+	mu  sync.Mutex
+	rtt time.Duration
+}
+
+type rttRecorderKey struct{}
+
+func withRTTRecorder(ctx context.Context) (context.Context, *rttRecorder) {
+	rec := &rttRecorder{}
+	return context.WithValue(ctx, rttRecorderKey{}, rec), rec
+}
+
+func recordRTT(ctx context.Context, rtt time.Duration) {
+	if rec, ok := ctx.Value(rttRecorderKey{}).(*rttRecorder); ok {
+		rec.mu.Lock()
+		rec.rtt = rtt
+		rec.mu.Unlock()
+	}
+}
+
+// the recorded RTT; zero when no query reported one
+func (rec *rttRecorder) value() time.Duration {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.rtt
+}
+
+// Forward lookup of `name` via `ns` as `rawLookupIP` does, along with the RTT
+// of the query answering each address (one query per family), for
+// `-answers-sorted-by-rtt`. Unlike `rawLookupIP`, an empty answer is an error,
+// as with `net.Resolver`
+func (r *Resolver) rawLookupIPTimed(ctx context.Context, ns nameServer, network NetworkString, name string) ([]net.IP, []time.Duration, error) {
+	var ips []net.IP
+	var rtts []time.Duration
+	for _, qtype := range queryTypes(network) {
+		queryCtx, rec := withRTTRecorder(ctx)
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(name), qtype)
+		resp, err := r.exchange(queryCtx, ns, msg)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ip := range addrsFromRRs(resp.Answer) {
+			ips = append(ips, ip)
+			rtts = append(rtts, rec.value())
+		}
+	}
+	if len(ips) == 0 {
+		return nil, nil, ErrNoAddresses
+	}
+	return ips, rtts, nil
+}
+
+// Order `addrs` by their lowest RTT in `rtts`, fastest first: a heuristic for
+// which addresses are "closest", as the RTT is that of the query to the
+// server rather than to the address. Addresses without an RTT are equal to
+// each other, so keep their order, after the others; when none has one, the
+// order is left as is
+func sortAddrsByRTT(addrs []string, rtts map[string]time.Duration) {
+	if len(rtts) == 0 {
+		return
+	}
+	sort.SliceStable(addrs, func(i, j int) bool {
+		a, aOK := rtts[addrs[i]]
+		b, bOK := rtts[addrs[j]]
+		if aOK != bOK {
+			return aOK
+		}
+		return a < b
+	})
+}
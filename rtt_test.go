@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortAddrsByRTT(t *testing.T) {
+	tests := []struct {
+		name  string
+		addrs []string
+		rtts  map[string]time.Duration
+		want  string
+	}{
+		{
+			name:  "all measured",
+			addrs: []string{"192.0.2.3", "192.0.2.1", "192.0.2.2"},
+			rtts:  map[string]time.Duration{"192.0.2.1": 30 * time.Millisecond, "192.0.2.2": 10 * time.Millisecond, "192.0.2.3": 20 * time.Millisecond},
+			want:  "192.0.2.2 192.0.2.3 192.0.2.1",
+		},
+		{
+			name:  "some missing",
+			addrs: []string{"192.0.2.9", "192.0.2.1", "192.0.2.5", "192.0.2.2"},
+			rtts:  map[string]time.Duration{"192.0.2.5": 20 * time.Millisecond, "192.0.2.2": 10 * time.Millisecond},
+			want:  "192.0.2.2 192.0.2.5 192.0.2.9 192.0.2.1",
+		},
+		{
+			name:  "none measured",
+			addrs: []string{"192.0.2.9", "2001:db8::1", "192.0.2.1"},
+			rtts:  map[string]time.Duration{},
+			want:  "192.0.2.9 2001:db8::1 192.0.2.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortAddrsByRTT(tt.addrs, tt.rtts)
+			if got := strings.Join(tt.addrs, " "); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+// the root servers' addresses, which are fixed (and published by IANA), so a
+// server answering them correctly is resolving as it should
+var selftestAddrs = []struct {
+	hostname   string
+	ipv4, ipv6 string
+}{
+	{"a.root-servers.net", "198.41.0.4", "2001:503:ba3e::2:30"},
+	{"j.root-servers.net", "192.58.128.30", "2001:503:c27::2:30"},
+	{"k.root-servers.net", "193.0.14.129", "2001:7fd::1"},
+	{"m.root-servers.net", "202.12.27.33", "2001:dc3::35"},
+}
+
+// the expectations for `-selftest`: the root servers' addresses of the
+// `network`'s family (ip4|ip6), or of both
+func selftestExpectations(network NetworkString) *expectations {
+	e := &expectations{label: "Self-test", addrs: map[string][]string{}}
+	for _, root := range selftestAddrs {
+		e.hostnames = append(e.hostnames, root.hostname)
+		key := expectKey(root.hostname)
+		if network != IPv6 {
+			e.addrs[key] = append(e.addrs[key], root.ipv4)
+		}
+		if network != IPv4 {
+			e.addrs[key] = append(e.addrs[key], root.ipv6)
+		}
+	}
+	return e
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// a DNS server and the `net.Resolver` dialing it
+type nameServer struct {
+	addr     string // empty when using the system's default DNS server
+	port     string // empty for the standard port (53)
+	resolver *net.Resolver
+}
+
+// the server's host:port
+func (ns nameServer) hostPort() string {
+	port := ns.port
+	if port == "" {
+		port = dnsPort
+	}
+	return net.JoinHostPort(ns.addr, port)
+}
+
+func (ns nameServer) String() string {
+	if ns.addr == "" {
+		return "default resolver"
+	}
+	return ns.addr
+}
+
+// the configured servers, in failover order
+func (r *Resolver) ServerAddrs() []string {
+	addrs := make([]string, len(r.servers))
+	for i, ns := range r.servers {
+		addrs[i] = ns.String()
+	}
+	return addrs
+}
+
+// Picks the starting server for each query, randomly when shuffling
+type serverPicker struct {
+	shuffle bool
+	mu      sync.Mutex
+	rng     *rand.Rand
+}
+
+// Randomize the per-query starting server among the configured servers;
+// `seed` makes the order reproducible
+func (r *Resolver) ShuffleServers(seed int64) {
+	r.picker.shuffle = true
+	r.picker.rng = rand.New(rand.NewSource(seed))
+}
+
+// the order to try the servers in for a single query: every server is
+// covered, in configured order after the starting point
+func (r *Resolver) serverOrder() []nameServer {
+	start := 0
+	if r.picker.shuffle && len(r.servers) > 1 {
+		r.picker.mu.Lock()
+		start = r.picker.rng.Intn(len(r.servers))
+		r.picker.mu.Unlock()
+	}
+
+	order := make([]nameServer, 0, len(r.servers))
+	for i := range r.servers {
+		order = append(order, r.servers[(start+i)%len(r.servers)])
+	}
+	return order
+}
+
+// NXDOMAIN (or no records) is an answer; anything else (timeouts, SERVFAIL,
+// refused connections) is worth retrying against the next server
+func shouldFailover(err error) bool {
+	var dnsErr *net.DNSError
+	var rcodeErr *RcodeError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	} else if errors.As(err, &rcodeErr) {
+		return rcodeErr.Rcode != dns.RcodeNameError
+	}
+	return true
+}
+
+// Run `lookup` against each server in turn until one answers, returning the
+// server used for the final attempt
+func (r *Resolver) withFailover(ctx context.Context, lookup func(ns nameServer) error) (nameServer, error) {
+	order := r.serverOrder()
+	var err error
+	for i, ns := range order {
+		err = lookup(ns)
+		if err == nil || !shouldFailover(err) || ctx.Err() != nil || i == len(order)-1 {
+			return ns, err
+		}
+		r.logWarning("Query via %s failed: '%s'; trying %s\n", ns, err.Error(), order[i+1])
+	}
+	return nameServer{}, err
+}
+
+// Records the server a lookup's queries were last sent to (host:port), since
+// `net.Resolver` doesn't report which server answered; the dial funcs record
+// into the recorder carried by the lookup's context
+type serverRecorder struct {
+	mu   sync.Mutex
+	addr string
+}
+
+type serverRecorderKey struct{}
+
+func withServerRecorder(ctx context.Context) (context.Context, *serverRecorder) {
+	rec := &serverRecorder{}
+	return context.WithValue(ctx, serverRecorderKey{}, rec), rec
+}
+
+func recordServer(ctx context.Context, addr string) {
+	if rec, ok := ctx.Value(serverRecorderKey{}).(*serverRecorder); ok {
+		rec.mu.Lock()
+		rec.addr = addr
+		rec.mu.Unlock()
+	}
+}
+
+// whether the lookup went through a dial func recording its server
+func (rec *serverRecorder) recorded() bool {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.addr != ""
+}
+
+// the recorded server, or `ns` when nothing was recorded (e.g. the system's
+// resolver didn't go through the dial func)
+func (rec *serverRecorder) serverOr(ns nameServer) string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.addr == "" {
+		return ns.String()
+	}
+	host, port, err := net.SplitHostPort(rec.addr)
+	if err == nil && port == dnsPort {
+		return host
+	}
+	return rec.addr
+}
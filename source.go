@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// the dialer for queries over `network` (udp|tcp), bound to the configured
+// source address if any
+func (r *Resolver) dialer(network string) *net.Dialer {
+	d := &net.Dialer{Control: r.dialControl}
+	if r.sourceIP != nil {
+		if strings.HasPrefix(network, "tcp") {
+			d.LocalAddr = &net.TCPAddr{IP: r.sourceIP}
+		} else {
+			d.LocalAddr = &net.UDPAddr{IP: r.sourceIP}
+		}
+	}
+	return d
+}
+
+// Set socket options on each query's socket before it's connected, via
+// `net.Dialer.Control` (e.g. SO_BINDTODEVICE, DSCP)
+func (r *Resolver) SetDialControl(control func(network, address string, c syscall.RawConn) error) {
+	r.dialControl = control
+}
+
+// Send the queries from `ip`, e.g. to pick the interface on a multi-homed
+// host; fails when the address can't be bound to (i.e. isn't local)
+func (r *Resolver) SetSourceIP(ip net.IP) error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+	if err != nil {
+		return fmt.Errorf("can't bind to source address %s: %w", ip, err)
+	}
+	conn.Close()
+	r.sourceIP = ip
+	return nil
+}
+
+// a Control func running each of the non-nil `controls` in turn, stopping at
+// the first error, e.g. to bind to a device and set the buffer sizes
+func chainDialControls(controls ...func(network, address string, c syscall.RawConn) error) func(network, address string, c syscall.RawConn) error {
+	var set []func(network, address string, c syscall.RawConn) error
+	for _, control := range controls {
+		if control != nil {
+			set = append(set, control)
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		for _, control := range set {
+			if err := control(network, address, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
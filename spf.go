@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	spfLookupLimit = 10 // DNS-querying mechanisms allowed during an SPF evaluation (RFC 7208 4.6.4)
+	spfMaxDepth    = 10 // guards against deep include chains, in addition to the loop check
+)
+
+var (
+	ErrNoSPFRecord       = errors.New("no SPF record")
+	ErrSPFLookupLimit    = errors.New("SPF lookup limit exceeded")
+	ErrMultipleSPFRecord = errors.New("multiple SPF records")
+	ErrSPFLoop           = errors.New("SPF include loop") // a permerror, as per RFC 7208 5.2
+)
+
+// state for expanding a single domain's SPF record
+type spfExpansion struct {
+	r         *Resolver
+	ctx       context.Context
+	network   NetworkString
+	expanding map[string]bool // the domains on the current include chain, to spot loops
+	lookups   int
+}
+
+// Fetch the SPF record for `domain` and recursively expand its `include:`,
+// `redirect=`, `a` and `mx` terms, counting the DNS-querying terms against the
+// RFC 7208 limit of 10; exceeding the limit fails the result
+func (r *Resolver) ExpandSPF(ctx context.Context, network NetworkString, domain string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: domain}
+
+	e := &spfExpansion{r: r, ctx: ctx, network: network, expanding: map[string]bool{}}
+	err := e.expand(domain, 0)
+	result.Duration = time.Since(startTime)
+	if err == nil && e.lookups > spfLookupLimit {
+		err = fmt.Errorf("%w: %d lookups (max %d)", ErrSPFLookupLimit, e.lookups, spfLookupLimit)
+	}
+	if err != nil {
+		r.logError("SPF expansion for %s failed: Error - '%s'\n", domain, err.Error())
+		result.Err = newResolveError(domain, err)
+		return result
+	}
+	r.logInfo("SPF for %s requires %d DNS lookups (max %d)\n", domain, e.lookups, spfLookupLimit)
+	return result
+}
+
+// the `v=spf1` TXT record for `domain`
+func (e *spfExpansion) fetch(domain string) (string, error) {
+	var txts []string
+	_, err := e.r.withFailover(e.ctx, func(ns nameServer) error {
+		var err error
+		txts, err = ns.resolver.LookupTXT(e.ctx, domain)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var records []string
+	for _, txt := range txts {
+		lower := strings.ToLower(txt)
+		if lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ") {
+			records = append(records, txt)
+		}
+	}
+	switch len(records) {
+	case 0:
+		return "", fmt.Errorf("%w for %s", ErrNoSPFRecord, domain)
+	case 1:
+		return records[0], nil
+	default:
+		return "", fmt.Errorf("%w for %s", ErrMultipleSPFRecord, domain)
+	}
+}
+
+// Expand `domain`'s record; a domain included from several places (e.g.
+// two includes sharing a third) is expanded, and counted, each time, as a
+// receiver evaluates it, while one including itself along the chain is a
+// loop, failing the expansion
+func (e *spfExpansion) expand(domain string, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	key := strings.ToLower(strings.TrimSuffix(domain, "."))
+	if e.expanding[key] {
+		return fmt.Errorf("%w: %s includes itself", ErrSPFLoop, domain)
+	}
+	if depth > spfMaxDepth {
+		return fmt.Errorf("SPF include chain deeper than %d at %s", spfMaxDepth, domain)
+	}
+	e.expanding[key] = true
+	defer delete(e.expanding, key)
+
+	record, err := e.fetch(domain)
+	if err != nil {
+		return err
+	}
+	e.r.logInfo("%sSPF for %s: %s\n", indent, domain, record)
+
+	for _, term := range strings.Fields(record)[1:] {
+		mechanism := strings.TrimLeft(term, "+-~?")
+		name, arg, _ := strings.Cut(mechanism, ":")
+		if modifier, value, ok := strings.Cut(mechanism, "="); ok {
+			name, arg = modifier, value
+		}
+		// a CIDR length straight after the mechanism, e.g. a/24 or mx/24//64
+		name, _, _ = strings.Cut(name, "/")
+
+		switch strings.ToLower(name) {
+		case "include", "redirect":
+			e.lookups++
+			e.r.logInfo("%s  %s -> %s (lookup %d)\n", indent, term, arg, e.lookups)
+			if strings.Contains(arg, "%") {
+				e.r.logInfo("%s  %s uses macros; not expanded\n", indent, arg)
+				continue
+			}
+			if err := e.expand(arg, depth+1); err != nil {
+				return err
+			}
+		case "a", "mx":
+			e.lookups++
+			target := domain
+			if arg != "" {
+				target = arg
+			}
+			// drop any CIDR length, e.g. a:mail.example.com/24
+			if i := strings.Index(target, "/"); i >= 0 {
+				target = target[:i]
+			}
+			if target == "" {
+				target = domain
+			}
+			e.r.logInfo("%s  %s -> %s (lookup %d): %s\n", indent, term, target, e.lookups, e.resolveTerm(strings.ToLower(name), target))
+		case "ptr", "exists":
+			e.lookups++
+			e.r.logInfo("%s  %s (lookup %d)\n", indent, term, e.lookups)
+		default:
+			// ip4, ip6, all, exp= and unknown modifiers don't query DNS
+			e.r.logInfo("%s  %s\n", indent, term)
+		}
+	}
+	return nil
+}
+
+// the addresses for an `a` term, or the exchanges (and their addresses) for an `mx` term
+func (e *spfExpansion) resolveTerm(mechanism string, target string) string {
+	if mechanism == "a" {
+		ips, _, err := e.r.lookupIP(e.ctx, e.network, target)
+		if err != nil {
+			return "error: " + shortError(err)
+		}
+		return addrString(ips)
+	}
+
+	var mxs []*net.MX
+	_, err := e.r.withFailover(e.ctx, func(ns nameServer) error {
+		var err error
+		mxs, err = ns.resolver.LookupMX(e.ctx, target)
+		return err
+	})
+	if err != nil {
+		return "error: " + shortError(err)
+	}
+	var hosts []string
+	for _, mx := range mxs {
+		ips, _, err := e.r.lookupIP(e.ctx, e.network, mx.Host)
+		if err != nil {
+			hosts = append(hosts, fmt.Sprintf("%s (error: %s)", mx.Host, shortError(err)))
+		} else {
+			hosts = append(hosts, fmt.Sprintf("%s (%s)", mx.Host, addrString(ips)))
+		}
+	}
+	return strings.Join(hosts, ", ")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpandSPF(t *testing.T) {
+	ts := newTestServer(t)
+	tests := []struct {
+		domain  string
+		lookups int
+		logged  []string
+		err     error
+	}{
+		{
+			domain: "spf.test",
+			// a/24, mx/24, a:ok.test/24, then both includes expanding the
+			// shared one (and its `a`) in full
+			lookups: 9,
+			logged: []string{
+				"a/24 -> spf.test (lookup 1): 192.0.2.20",
+				"mx/24 -> spf.test (lookup 2): v4.test. (192.0.2.4)",
+				"a:ok.test/24 -> ok.test (lookup 3): 192.0.2.1",
+				"include:shared.spf.test -> shared.spf.test (lookup 5)",
+				"include:shared.spf.test -> shared.spf.test (lookup 8)",
+			},
+		},
+		{
+			domain:  "loop.spf.test",
+			lookups: 2,
+			logged:  []string{"include:loop.spf.test -> loop.spf.test (lookup 2)"},
+			err:     ErrSPFLoop,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			r := newTestResolver(t, ts, nil)
+			r.noLog = false
+			stdout, stderr := captureLogs(t)
+
+			e := &spfExpansion{r: r, ctx: testContext(t), network: IPv4, expanding: map[string]bool{}}
+			if err := e.expand(tt.domain, 0); !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if e.lookups != tt.lookups {
+				t.Errorf("got %d lookups, want %d", e.lookups, tt.lookups)
+			}
+			logs := stdout.String() + stderr.String()
+			for _, line := range tt.logged {
+				if !strings.Contains(logs, line) {
+					t.Errorf("log %q doesn't contain %q", logs, line)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandSPFLoopFails(t *testing.T) {
+	ts := newTestServer(t)
+	r := newTestResolver(t, ts, nil)
+	result := r.ExpandSPF(testContext(t), IPv4, "loop.spf.test")
+	if !errors.Is(result.Err, ErrSPFLoop) {
+		t.Fatalf("got error %v, want %v", result.Err, ErrSPFLoop)
+	}
+	if !r.Failed(result) {
+		t.Error("a loop doesn't fail the result")
+	}
+}
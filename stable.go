@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// the `-sortkey` orderings of the `-stable` output, each comparing two results
+// (negative when `a` comes first); ties fall back to the hostname
+var resultSortKeys = map[string]func(a, b *ResolveResult) int{
+	"hostname": func(a, b *ResolveResult) int {
+		return 0
+	},
+	// by the first (lowest) address, with the failed hostnames last
+	"ip": func(a, b *ResolveResult) int {
+		if len(a.IPs) == 0 || len(b.IPs) == 0 {
+			return len(b.IPs) - len(a.IPs)
+		}
+		return compareIPs(a.IPs[0], b.IPs[0])
+	},
+	// the most addresses first
+	"count": func(a, b *ResolveResult) int {
+		return len(b.IPs) - len(a.IPs)
+	},
+	// the slowest first
+	"latency": func(a, b *ResolveResult) int {
+		switch {
+		case a.Duration > b.Duration:
+			return -1
+		case a.Duration < b.Duration:
+			return 1
+		}
+		return 0
+	},
+}
+
+func resultSortKeyNames() string {
+	names := make([]string, 0, len(resultSortKeys))
+	for name := range resultSortKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Sorts the results by `key` (see `resultSortKeys`), and each result's
+// addresses and reverse names, so the output of repeated runs can be diffed;
+// the results come back in input order, which depends on how the hostnames
+// were given
+func stableResults(results []*ResolveResult, key func(a, b *ResolveResult) int) []*ResolveResult {
+	sorted := make([]*ResolveResult, len(results))
+	copy(sorted, results)
+	for _, result := range sorted {
+		sortIPs(result.IPs)
+		for _, names := range result.Reverse {
+			sort.Strings(names)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if c := key(sorted[i], sorted[j]); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Hostname < sorted[j].Hostname
+	})
+	return sorted
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// the outcomes of the hostnames under one registrable domain
+type suffixStat struct {
+	domain                      string
+	total, failed               int
+	notFound, servFail, timeout int
+}
+
+func (s *suffixStat) failureRate() float64 {
+	return float64(s.failed) / float64(s.total)
+}
+
+// the registrable domain (eTLD+1, per the public suffix list) of the name
+// queried for `result`, e.g. "example.co.uk" for "www.example.co.uk"; a name
+// without one (e.g. a bare public suffix) is its own group
+func registrableDomain(result *ResolveResult) string {
+	name := result.Hostname
+	if result.QueryName != "" {
+		name = result.QueryName
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	domain, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		return name
+	}
+	return domain
+}
+
+// Group the results by registrable domain, highest failure rate first (then
+// the most failures, then by domain), to spot the problematic zones in a
+// large audit
+func suffixStats(results []*ResolveResult) []*suffixStat {
+	byDomain := map[string]*suffixStat{}
+	var stats []*suffixStat
+	for _, result := range results {
+		domain := registrableDomain(result)
+		stat, ok := byDomain[domain]
+		if !ok {
+			stat = &suffixStat{domain: domain}
+			byDomain[domain] = stat
+			stats = append(stats, stat)
+		}
+		stat.total++
+		if result.Err == nil {
+			continue
+		}
+		stat.failed++
+		var resolveErr *ResolveError
+		if errors.As(result.Err, &resolveErr) {
+			switch {
+			case resolveErr.NotFound:
+				stat.notFound++
+			case resolveErr.ServFail:
+				stat.servFail++
+			case resolveErr.Timeout:
+				stat.timeout++
+			}
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.failureRate() != b.failureRate() {
+			return a.failureRate() > b.failureRate()
+		}
+		if a.failed != b.failed {
+			return a.failed > b.failed
+		}
+		return a.domain < b.domain
+	})
+	return stats
+}
+
+// log the results' success and failure rates per registrable domain as a table
+func logSuffixStats(results []*ResolveResult) {
+	var table strings.Builder
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOMAIN\tHOSTNAMES\tRESOLVED\tFAILED\tFAILURE RATE\tNXDOMAIN\tSERVFAIL\tTIMEOUT")
+	for _, stat := range suffixStats(results) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.0f%%\t%d\t%d\t%d\n", stat.domain, stat.total, stat.total-stat.failed, stat.failed,
+			100*stat.failureRate(), stat.notFound, stat.servFail, stat.timeout)
+	}
+	w.Flush()
+
+	LogSummary("Results by registrable domain:\n")
+	for _, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		LogSummary("%s\n", line)
+	}
+}
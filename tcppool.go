@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Keeps the TCP connections to the DNS servers open between queries, so
+// queries over TCP reuse them rather than dialing (and handshaking) one per
+// query, as RFC 7766 allows; each connection carries one query at a time
+type tcpPool struct {
+	mu   sync.Mutex
+	idle map[string][]*dns.Conn // keyed by server address (host:port)
+}
+
+func newTCPPool() *tcpPool {
+	return &tcpPool{idle: map[string][]*dns.Conn{}}
+}
+
+// an idle connection to `addr`, or nil if there's none
+func (p *tcpPool) get(addr string) *dns.Conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conns := p.idle[addr]
+	if len(conns) == 0 {
+		return nil
+	}
+	conn := conns[len(conns)-1]
+	p.idle[addr] = conns[:len(conns)-1]
+	return conn
+}
+
+// return `conn` to the pool once its query is answered
+func (p *tcpPool) put(addr string, conn *dns.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idle[addr] = append(p.idle[addr], conn)
+}
+
+// close the idle connections, e.g. once a run (or an `-interval` cycle) is done
+func (p *tcpPool) closeIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, conns := range p.idle {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		delete(p.idle, addr)
+	}
+}
+
+// Close the TCP connections kept open by `-tcp-keepalive`, if any; later
+// queries dial new ones
+func (r *Resolver) CloseIdleConnections() {
+	if r.tcpPool != nil {
+		r.tcpPool.closeIdle()
+	}
+}
+
+// Send `msg` to `addr` over a pooled TCP connection, dialing one when none is
+// idle. The server may have closed an idle connection in the meantime, so a
+// failed query over a reused connection is retried once over a new one
+func (r *Resolver) exchangePooled(ctx context.Context, client *dns.Client, msg *dns.Msg, addr string) (*dns.Msg, time.Duration, error) {
+	if conn := r.tcpPool.get(addr); conn != nil {
+		resp, rtt, err := client.ExchangeWithConnContext(ctx, msg, conn)
+		if err == nil {
+			r.tcpPool.put(addr, conn)
+			return resp, rtt, nil
+		}
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, 0, err
+		}
+		r.logDebug("Reused connection to %s failed ('%s'); dialing a new one\n", addr, err.Error())
+	}
+
+	conn, err := client.DialContext(ctx, addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, rtt, err := client.ExchangeWithConnContext(ctx, msg, conn)
+	if err != nil {
+		conn.Close()
+		return nil, 0, err
+	}
+	r.tcpPool.put(addr, conn)
+	return resp, rtt, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestTCPPoolReuse(t *testing.T) {
+	ts := newTestServer(t)
+	var dials atomic.Int64
+	r := newTestResolver(t, ts, &dials)
+	r.tcpPool = newTCPPool()
+
+	query := func() {
+		t.Helper()
+		msg := new(dns.Msg)
+		msg.SetQuestion("ok.test.", dns.TypeA)
+		if _, err := r.exchangeOver(testContext(t), r.servers[0], msg, "tcp"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	query()
+	query()
+	query()
+	if got := dials.Load(); got != 1 {
+		t.Errorf("got %d dials for 3 queries, want 1", got)
+	}
+	if got := ts.queries.Load(); got != 3 {
+		t.Errorf("the server got %d queries, want 3", got)
+	}
+
+	// the idle connection is closed, so the next query dials a new one
+	r.CloseIdleConnections()
+	if n := len(r.tcpPool.idle); n != 0 {
+		t.Errorf("%d servers still have idle connections", n)
+	}
+	query()
+	if got := dials.Load(); got != 2 {
+		t.Errorf("got %d dials after closing the idle connections, want 2", got)
+	}
+}
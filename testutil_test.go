@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// the canned zone served by `testServer`; names not listed here get NXDOMAIN
+var testZone = map[string][]string{
+	"ok.test.":                {"ok.test. 300 IN A 192.0.2.1", "ok.test. 300 IN AAAA 2001:db8::1"},
+	"v4.test.":                {"v4.test. 300 IN A 192.0.2.4"},
+	"v6.test.":                {"v6.test. 300 IN AAAA 2001:db8::6"},
+	"multi.test.":             {"multi.test. 300 IN A 192.0.2.2", "multi.test. 300 IN A 192.0.2.3"},
+	"1.2.0.192.in-addr.arpa.": {"1.2.0.192.in-addr.arpa. 300 IN PTR ok.test."},
+	"4.2.0.192.in-addr.arpa.": {"4.2.0.192.in-addr.arpa. 300 IN PTR v4.test."},
+	"alias.test.":             {"alias.test. 300 IN CNAME ok.test."},
+	"anyfamily.test.": {
+		"anyfamily.test. 300 IN A 192.0.2.40",
+		"anyfamily.test. 300 IN AAAA 2001:db8::40",
+		"anyfamily.test. 300 IN AAAA ::ffff:192.0.2.41",
+	},
+	"nodata.test.":               {`nodata.test. 300 IN TXT "no addresses"`},
+	"www.fallback.test.":         {"www.fallback.test. 300 IN A 192.0.2.30"},
+	"nodata.test.fallback.test.": {"nodata.test.fallback.test. 300 IN A 192.0.2.31"},
+	"spf.test.": {
+		`spf.test. 300 IN TXT "v=spf1 a/24 mx/24 a:ok.test/24 include:inc1.spf.test include:inc2.spf.test -all"`,
+		"spf.test. 300 IN A 192.0.2.20",
+		"spf.test. 300 IN MX 10 v4.test.",
+	},
+	"inc1.spf.test.":   {`inc1.spf.test. 300 IN TXT "v=spf1 include:shared.spf.test ~all"`},
+	"inc2.spf.test.":   {`inc2.spf.test. 300 IN TXT "v=spf1 include:shared.spf.test ~all"`},
+	"shared.spf.test.": {`shared.spf.test. 300 IN TXT "v=spf1 a -all"`, "shared.spf.test. 300 IN A 192.0.2.21"},
+	"loop.spf.test.":   {`loop.spf.test. 300 IN TXT "v=spf1 include:loop2.spf.test -all"`},
+	"loop2.spf.test.":  {`loop2.spf.test. 300 IN TXT "v=spf1 ip4:192.0.2.0/24 include:loop.spf.test -all"`},
+}
+
+// A DNS server on a random localhost port (UDP and TCP) answering from
+// `testZone`, except for names starting with `servfail.` (SERVFAIL) and
+// `timeout.` (no response at all); names starting with `anyfamily.` are
+// answered with all of their records whatever the type queried, as a
+// misbehaving server might
+type testServer struct {
+	addr        string       // host:port
+	delay       atomic.Int64 // how long each answer is held back, in nanoseconds
+	queries     atomic.Int64
+	lastID      atomic.Uint32 // the transaction ID of the last query
+	inFlight    atomic.Int64
+	maxInFlight atomic.Int64 // the most queries being answered at once
+}
+
+func newTestServer(t testing.TB) *testServer {
+	t.Helper()
+	ts := &testServer{}
+	handler := dns.HandlerFunc(ts.serveDNS)
+
+	// bind UDP on a random port, then TCP on the same one
+	var udpConn net.PacketConn
+	var tcpListener net.Listener
+	for i := 0; ; i++ {
+		var err error
+		udpConn, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listening on udp: %v", err)
+		}
+		tcpListener, err = net.Listen("tcp", udpConn.LocalAddr().String())
+		if err == nil {
+			break
+		}
+		udpConn.Close()
+		if i >= 10 {
+			t.Fatalf("listening on tcp: %v", err)
+		}
+	}
+	ts.addr = udpConn.LocalAddr().String()
+
+	var started sync.WaitGroup
+	started.Add(2)
+	udpServer := &dns.Server{PacketConn: udpConn, Handler: handler, NotifyStartedFunc: started.Done}
+	tcpServer := &dns.Server{Listener: tcpListener, Handler: handler, NotifyStartedFunc: started.Done}
+	go udpServer.ActivateAndServe()
+	go tcpServer.ActivateAndServe()
+	started.Wait()
+	t.Cleanup(func() {
+		udpServer.Shutdown()
+		tcpServer.Shutdown()
+	})
+	return ts
+}
+
+func (ts *testServer) serveDNS(w dns.ResponseWriter, req *dns.Msg) {
+	ts.queries.Add(1)
+	ts.lastID.Store(uint32(req.Id))
+	if n := ts.inFlight.Add(1); n > ts.maxInFlight.Load() {
+		// good enough for the tests: a lost race only under-reports
+		ts.maxInFlight.Store(n)
+	}
+	defer ts.inFlight.Add(-1)
+	time.Sleep(time.Duration(ts.delay.Load()))
+	q := req.Question[0]
+	name := strings.ToLower(q.Name)
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Authoritative = true
+	switch {
+	case strings.HasPrefix(name, "timeout."):
+		return
+	case strings.HasPrefix(name, "servfail."):
+		resp.Rcode = dns.RcodeServerFailure
+	default:
+		records, ok := testZone[name]
+		if !ok {
+			resp.Rcode = dns.RcodeNameError
+			break
+		}
+		for _, record := range records {
+			rr, err := dns.NewRR(record)
+			if err != nil {
+				panic(err)
+			}
+			if rr.Header().Rrtype == q.Qtype || rr.Header().Rrtype == dns.TypeCNAME || strings.HasPrefix(name, "anyfamily.") {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+		// follow a CNAME within the zone, as a recursive resolver would
+		for _, rr := range resp.Answer {
+			if cname, ok := rr.(*dns.CNAME); ok {
+				for _, record := range testZone[cname.Target] {
+					if rr, _ := dns.NewRR(record); rr != nil && rr.Header().Rrtype == q.Qtype {
+						resp.Answer = append(resp.Answer, rr)
+					}
+				}
+			}
+		}
+	}
+	w.WriteMsg(resp)
+}
+
+// A quiet `Resolver` sending its queries to `ts`, both via the Go resolver
+// and the lower-level client; `dials` (if not nil) counts the sockets opened
+func newTestResolver(t testing.TB, ts *testServer, dials *atomic.Int64) *Resolver {
+	t.Helper()
+	host, port, err := net.SplitHostPort(ts.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewResolver()
+	ns := nameServer{addr: host, port: port}
+	ns.resolver = r.newNetResolver(ns.hostPort())
+	r.servers = []nameServer{ns}
+	r.DisableLogging()
+	if dials != nil {
+		r.SetDialControl(func(network, address string, c syscall.RawConn) error {
+			dials.Add(1)
+			return nil
+		})
+	}
+	return r
+}
+
+// Capture the global logger's output (stdout's and stderr's) for the test,
+// without timestamps
+func captureLogs(t testing.TB) (stdout, stderr *bytes.Buffer) {
+	t.Helper()
+	maybeInitializeLogger()
+	saved := *globalLogger
+	stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+	globalLogger.infoLogger = log.New(stdout, "", 0)
+	globalLogger.errorLogger = log.New(stderr, "", 0)
+	t.Cleanup(func() { *globalLogger = saved })
+	return stdout, stderr
+}
+
+// a context for a single test lookup, short enough for the `timeout.` names
+func testContext(t testing.TB) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestTestServer(t *testing.T) {
+	ts := newTestServer(t)
+	r := newTestResolver(t, ts, nil)
+
+	tests := []struct {
+		hostname string
+		want     []string
+		notFound bool
+		servFail bool
+		timeout  bool
+	}{
+		{hostname: "ok.test", want: []string{"192.0.2.1", "2001:db8::1"}},
+		{hostname: "nxdomain.test", notFound: true},
+		{hostname: "servfail.test", servFail: true},
+		{hostname: "timeout.test", timeout: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostname, func(t *testing.T) {
+			ctx := testContext(t)
+			if tt.timeout {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, 200*time.Millisecond)
+				defer cancel()
+			}
+			result := r.ResolveHostname(ctx, "ip", tt.hostname)
+			if len(tt.want) > 0 {
+				if result.Err != nil {
+					t.Fatalf("unexpected error: %v", result.Err)
+				}
+				sortIPs(result.IPs)
+				if got := addrString(result.IPs); got != strings.Join(tt.want, ", ") {
+					t.Errorf("got %s, want %v", got, tt.want)
+				}
+				return
+			}
+			resolveErr, ok := result.Err.(*ResolveError)
+			if !ok {
+				t.Fatalf("got error %v, want a *ResolveError", result.Err)
+			}
+			if resolveErr.NotFound != tt.notFound || resolveErr.ServFail != tt.servFail || resolveErr.Timeout != tt.timeout {
+				t.Errorf("got %+v, want notFound=%t servFail=%t timeout=%t", resolveErr, tt.notFound, tt.servFail, tt.timeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// escape a Prometheus label value (backslash, double quote and newline)
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Write the run's results as Prometheus text-format metrics to `path`, for
+// node_exporter's textfile collector: per-hostname success (1/0) and
+// duration, labeled by hostname. The file is written to a temporary file
+// alongside and renamed over `path`, so the collector never reads a partial one
+func writeTextfile(path string, results []*ResolveResult, r *Resolver, duration time.Duration) error {
+	// a hostname listed twice would otherwise be a duplicate series
+	seen := map[string]bool{}
+	var unique []*ResolveResult
+	for _, result := range results {
+		if !seen[result.Hostname] {
+			seen[result.Hostname] = true
+			unique = append(unique, result)
+		}
+	}
+	results = unique
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "# HELP resolve_hostname_success Whether the hostname resolved (1) or failed (0).")
+	fmt.Fprintln(&buf, "# TYPE resolve_hostname_success gauge")
+	for _, result := range results {
+		success := 1
+		if r.Failed(result) {
+			success = 0
+		}
+		fmt.Fprintf(&buf, "resolve_hostname_success{hostname=\"%s\"} %d\n", labelEscaper.Replace(result.Hostname), success)
+	}
+	fmt.Fprintln(&buf, "# HELP resolve_hostname_duration_seconds How long resolving the hostname took, including its reverse lookups.")
+	fmt.Fprintln(&buf, "# TYPE resolve_hostname_duration_seconds gauge")
+	for _, result := range results {
+		fmt.Fprintf(&buf, "resolve_hostname_duration_seconds{hostname=\"%s\"} %g\n", labelEscaper.Replace(result.Hostname), result.Duration.Seconds())
+	}
+	fmt.Fprintln(&buf, "# HELP resolve_hostname_run_duration_seconds How long the whole run took.")
+	fmt.Fprintln(&buf, "# TYPE resolve_hostname_run_duration_seconds gauge")
+	fmt.Fprintf(&buf, "resolve_hostname_run_duration_seconds %g\n", duration.Seconds())
+	fmt.Fprintln(&buf, "# HELP resolve_hostname_last_run_timestamp_seconds When the run completed, in seconds since the epoch.")
+	fmt.Fprintln(&buf, "# TYPE resolve_hostname_last_run_timestamp_seconds gauge")
+	fmt.Fprintf(&buf, "resolve_hostname_last_run_timestamp_seconds %d\n", time.Now().Unix())
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// write `data` to a temporary file alongside `path`, then rename it into
+// place, so a reader (or the next run) never sees a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // a no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// the names of the TLSA fields' values (RFC 6698, RFC 7218)
+var (
+	tlsaUsages    = map[uint8]string{0: "PKIX-TA", 1: "PKIX-EE", 2: "DANE-TA", 3: "DANE-EE"}
+	tlsaSelectors = map[uint8]string{0: "Cert", 1: "SPKI"}
+	tlsaMatching  = map[uint8]string{0: "Full", 1: "SHA2-256", 2: "SHA2-512"}
+)
+
+// the TLSA owner name for a service on `port` over `proto` at `hostname`,
+// e.g. "_443._tcp.example.com"
+func tlsaName(hostname string, port int, proto string) string {
+	return fmt.Sprintf("_%d._%s.%s", port, proto, hostname)
+}
+
+// Look up the TLSA records (DANE) for the service on the configured port and
+// protocol at `hostname` via the lower-level client, logging each record's
+// usage, selector, matching type and certificate association data. A name
+// without TLSA records, or that doesn't exist, is reported as "DANE not
+// configured" rather than failing
+func (r *Resolver) ResolveTLSA(ctx context.Context, hostname string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	name := tlsaName(hostname, r.tlsaPort, r.tlsaProto)
+	result.QueryName = name
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), dns.TypeTLSA)
+	var resp *dns.Msg
+	queryCtx, answered := withServerRecorder(ctx)
+	ns, err := r.withFailover(queryCtx, func(ns nameServer) error {
+		var err error
+		resp, err = r.exchange(queryCtx, ns, msg)
+		return err
+	})
+	result.Server = answered.serverOr(ns)
+	result.Duration = time.Since(startTime)
+	var rcodeErr *RcodeError
+	if errors.As(err, &rcodeErr) && rcodeErr.Rcode == dns.RcodeNameError {
+		r.logInfo("TLSA records for %s via %s: none (DANE not configured)\n", r.displayName(name), result.Server)
+		return result
+	} else if err != nil {
+		r.logError("Failed to look up TLSA records for %s via %s: Error - '%s'\n", name, result.Server, err.Error())
+		result.Err = newResolveError(hostname, err)
+		return result
+	}
+
+	var records []*dns.TLSA
+	for _, rr := range resp.Answer {
+		if tlsa, ok := rr.(*dns.TLSA); ok {
+			records = append(records, tlsa)
+		}
+	}
+	if len(records) == 0 {
+		r.logInfo("TLSA records for %s via %s: none (DANE not configured)\n", r.displayName(name), result.Server)
+		return result
+	}
+
+	r.logInfo("TLSA records for %s via %s: %d\n", r.displayName(name), result.Server, len(records))
+	for _, tlsa := range records {
+		record := fmt.Sprintf("usage=%d (%s) selector=%d (%s) matching-type=%d (%s) data=%s",
+			tlsa.Usage, tlsaFieldName(tlsaUsages, tlsa.Usage),
+			tlsa.Selector, tlsaFieldName(tlsaSelectors, tlsa.Selector),
+			tlsa.MatchingType, tlsaFieldName(tlsaMatching, tlsa.MatchingType),
+			tlsa.Certificate)
+		result.Records = append(result.Records, record)
+		r.logInfo("  %s\n", record)
+	}
+	return result
+}
+
+// the name of a TLSA field's `value`, or "unassigned"
+func tlsaFieldName(names map[uint8]string, value uint8) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return "unassigned"
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// the OTLP/HTTP path traces are sent to when the endpoint doesn't give one
+	otlpTracesPath = "/v1/traces"
+	// how long to wait for the spans still queued to be exported at exit
+	tracingShutdownTimeout = 5 * time.Second
+)
+
+// Export a span per `ResolveHostname`, with a child span per reverse lookup,
+// to the OpenTelemetry collector at `endpoint` (OTLP over HTTP, e.g.
+// "http://localhost:4318"). The returned func flushes the spans still queued,
+// so must be called before exiting. Without it, no spans are created at all
+func (r *Resolver) EnableTracing(ctx context.Context, endpoint string) (shutdown func(context.Context) error, err error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = otlpTracesPath
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(u.String()))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("resolve-hostname"))),
+	)
+	r.tracer = provider.Tracer("resolve-hostname")
+	return provider.Shutdown, nil
+}
+
+// end `span` with the outcome of its lookup: `attrs` (e.g. the number of
+// answers), and `err` marking the span as failed
+func endSpan(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, shortError(err))
+	}
+	span.End()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how long `-transform-cmd` may take for each hostname, and how long its
+// output is still waited for once it's killed (e.g. held open by a child)
+const (
+	transformTimeout   = 10 * time.Second
+	transformWaitDelay = time.Second
+)
+
+// Pipe each hostname through the shell command `cmd` (on its stdin), querying
+// the first line of its output instead, e.g. `sed 's/$/.corp.example.com/'`
+// for bespoke qualification rules. The commands run concurrently, a few at a
+// time, and the hostnames keep their order. A hostname whose command fails,
+// times out or outputs nothing (or a line that isn't a valid hostname) is
+// skipped with an error; the number skipped is returned. Hostnames
+// transformed to the same name are only queried once
+func transformHostnames(ctx context.Context, cmd string, hostnames []string, allowWildcard bool) ([]string, int) {
+	transformed := make([]string, len(hostnames))
+	errs := make([]error, len(hostnames))
+	limit := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i, hostname := range hostnames {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			transformed[i], errs[i] = transformHostname(ctx, cmd, hostname, allowWildcard)
+		}()
+	}
+	wg.Wait()
+
+	var kept []string
+	skipped := 0
+	seen := map[string]string{} // the first hostname transformed to each name
+	for i, hostname := range hostnames {
+		if errs[i] != nil {
+			skipped++
+			LogError("Skipping %s: -transform-cmd failed: %s\n", hostname, errs[i].Error())
+			continue
+		}
+		if transformed[i] != hostname {
+			LogDebug("Transformed %s to %s\n", hostname, transformed[i])
+		}
+		key := expectKey(transformed[i])
+		if first, ok := seen[key]; ok {
+			LogWarning("Dropping %s: -transform-cmd transformed it to %s, as it did %s\n", hostname, transformed[i], first)
+			continue
+		}
+		seen[key] = hostname
+		kept = append(kept, transformed[i])
+	}
+	return kept, skipped
+}
+
+func transformHostname(ctx context.Context, cmd string, hostname string, allowWildcard bool) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, transformTimeout)
+	defer cancel()
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	setProcessGroup(c)
+	c.WaitDelay = transformWaitDelay
+	c.Stdin = strings.NewReader(hostname + "\n")
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("timed out after %s", formatDuration(transformTimeout))
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w ('%s')", err, msg)
+		}
+		return "", err
+	}
+	name, _, _ := strings.Cut(stdout.String(), "\n")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("no output")
+	}
+	if err := validateHostname(name, allowWildcard); err != nil {
+		return "", fmt.Errorf("invalid output '%s': %w", name, err)
+	}
+	return name, nil
+}
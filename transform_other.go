@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// process groups are only supported on Unix; elsewhere only the shell itself
+// is killed on timeout, and `WaitDelay` bounds the wait for its children
+func setProcessGroup(c *exec.Cmd) {}
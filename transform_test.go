@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransformHostnames(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmd       string
+		hostnames []string
+		want      []string
+		skipped   int
+	}{
+		{"suffix", "sed 's/$/.example.com/'", []string{"db1", "db2"}, []string{"db1.example.com", "db2.example.com"}, 0},
+		{"first line", "echo one.test; echo two.test", []string{"x"}, []string{"one.test"}, 0},
+		{"no output", "grep -v skip", []string{"skip.test", "keep.test"}, []string{"keep.test"}, 1},
+		{"failure", "exit 3", []string{"a.test"}, nil, 1},
+		{"invalid output", "sed 's/^bad/bad../'", []string{"bad.test", "good.test"}, []string{"good.test"}, 1},
+		{"duplicates", "tr A-Z a-z", []string{"Web.test", "web.test", "WEB.test.", "db.test"}, []string{"web.test", "db.test"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLogs(t)
+			got, skipped := transformHostnames(testContext(t), tt.cmd, tt.hostnames, false)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") || skipped != tt.skipped {
+				t.Errorf("got %v (%d skipped), want %v (%d skipped)", got, skipped, tt.want, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestTransformTimeoutKillsChildren(t *testing.T) {
+	// the shell's children hold its output open; they must be killed with it
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := transformHostname(ctx, "sleep 30 | cat", "a.test", false); err == nil {
+		t.Fatal("got no error, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed >= transformWaitDelay {
+		t.Errorf("took %s, want the pipeline killed at the timeout", elapsed)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Resolve `hostname` over both UDP and TCP via the lower-level client, logging
+// a warning when the (normalized) answers differ, which can point to a
+// middlebox tampering with one transport or to truncation bugs
+func (r *Resolver) ResolveCompareTransport(ctx context.Context, network NetworkString, hostname string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	queryCtx, answered := withServerRecorder(ctx)
+	for _, qtype := range queryTypes(network) {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(hostname), qtype)
+		var udpResp, tcpResp *dns.Msg
+		var udpErr, tcpErr error
+		ns, _ := r.withFailover(queryCtx, func(ns nameServer) error {
+			udpResp, udpErr = r.exchangeOver(queryCtx, ns, msg, "udp")
+			tcpResp, tcpErr = r.exchangeOver(queryCtx, ns, msg, "tcp")
+			// fail over only when neither transport got an answer
+			if udpResp == nil && tcpResp == nil {
+				return udpErr
+			}
+			return nil
+		})
+		result.Server = answered.serverOr(ns)
+		qtypeName := dns.TypeToString[qtype]
+
+		switch {
+		case udpResp == nil && tcpResp == nil:
+			r.logError("Failed to resolve %s %s over UDP and TCP via %s: Error - '%s' / '%s'\n", hostname, qtypeName, result.Server, shortError(udpErr), shortError(tcpErr))
+			result.Err = newResolveError(hostname, udpErr)
+			continue
+		case udpResp == nil:
+			r.logWarning("Differs for %s %s via %s: failed over UDP ('%s'), TCP: %s\n", hostname, qtypeName, result.Server, shortError(udpErr), transportAnswer(tcpResp))
+		case tcpResp == nil:
+			r.logWarning("Differs for %s %s via %s: failed over TCP ('%s'), UDP: %s\n", hostname, qtypeName, result.Server, shortError(tcpErr), transportAnswer(udpResp))
+		case transportAnswer(udpResp) != transportAnswer(tcpResp):
+			truncated := ""
+			if udpResp.Truncated {
+				truncated = " (the UDP response was truncated)"
+			}
+			r.logWarning("Differs for %s %s via %s%s: UDP: %s, TCP: %s\n", hostname, qtypeName, result.Server, truncated, transportAnswer(udpResp), transportAnswer(tcpResp))
+		default:
+			r.logInfo("Matches for %s %s over UDP and TCP via %s: %s\n", hostname, qtypeName, result.Server, transportAnswer(udpResp))
+		}
+
+		// TCP isn't subject to truncation, so its answer is the one kept
+		resp := tcpResp
+		if resp == nil {
+			resp = udpResp
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			result.Err = newResolveError(hostname, &RcodeError{Rcode: resp.Rcode})
+			continue
+		}
+		result.IPs = append(result.IPs, addrsFromRRs(resp.Answer)...)
+	}
+	result.Duration = time.Since(startTime)
+	if result.Err == nil && len(result.IPs) == 0 {
+		result.Err = newResolveError(hostname, ErrNoAddresses)
+	}
+	if !r.rawIPv6 {
+		result.IPs = unmapIPv4(result.IPs)
+	}
+	return result
+}
+
+// the rcode and answer records of `resp` normalized for comparison: sorted,
+// with lowercased names and without the TTLs, which a cache counts down
+func transportAnswer(resp *dns.Msg) string {
+	records := make([]string, len(resp.Answer))
+	for i, rr := range resp.Answer {
+		hdr := rr.Header()
+		records[i] = fmt.Sprintf("%s %s %s", strings.ToLower(hdr.Name), dns.TypeToString[hdr.Rrtype], strings.TrimSpace(strings.TrimPrefix(rr.String(), hdr.String())))
+	}
+	sort.Strings(records)
+	return fmt.Sprintf("%s [%s]", dns.RcodeToString[resp.Rcode], strings.Join(records, ", "))
+}
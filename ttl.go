@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Query the answer records for a resolved hostname via the lower-level client
+// (`net.Resolver` doesn't expose TTLs), following its CNAME chain, recording
+// the lowest TTL (the effective one, for which the answer is cached) and
+// flagging any below the configured minimum
+func (r *Resolver) checkTTL(ctx context.Context, network NetworkString, result *ResolveResult) {
+	if (r.minTTL <= 0 && !r.queryTTL && !r.showTTL) || result.Err != nil {
+		return
+	}
+	name := result.Hostname
+	if result.QueryName != "" {
+		name = result.QueryName
+	}
+
+	var answers []dns.RR
+	for _, qtype := range queryTypes(network) {
+		rrs, err := r.lookupChain(ctx, name, qtype)
+		if err != nil {
+			r.logWarning("Failed to check the TTLs for %s: '%s'\n", name, err.Error())
+			return
+		}
+		answers = append(answers, rrs...)
+	}
+
+	for _, rr := range answers {
+		ttl := time.Duration(rr.Header().Ttl) * time.Second
+		if !result.HasTTL || ttl < result.TTL {
+			result.TTL = ttl
+			result.HasTTL = true
+		}
+		if r.minTTL <= 0 || ttl >= r.minTTL {
+			continue
+		}
+		record := fmt.Sprintf("%s %s %s", dns.TypeToString[rr.Header().Rrtype], r.displayName(rr.Header().Name), rrStrings([]dns.RR{rr}))
+		if result.TTLErr == nil {
+			result.TTLErr = fmt.Errorf("%w: %s has TTL %ds (min %ds)", ErrTTLBelowMinimum, record, rr.Header().Ttl, int64(r.minTTL.Seconds()))
+		}
+		if r.minTTLFatal {
+			r.logError("%s: %s has TTL %ds, below the minimum of %ds\n", result.Hostname, record, rr.Header().Ttl, int64(r.minTTL.Seconds()))
+		} else {
+			r.logWarning("%s: %s has TTL %ds, below the minimum of %ds\n", result.Hostname, record, rr.Header().Ttl, int64(r.minTTL.Seconds()))
+		}
+	}
+	if r.showTTL && result.HasTTL {
+		r.logTTLs(result.Hostname, name, answers, result.TTL)
+	}
+}
+
+// Log the effective TTL of the answer for `name`; behind a CNAME chain, along
+// with each hop's own TTL, e.g. `www.example.com CNAME cdn.example.net 300s ->
+// cdn.example.net A 20s; effective TTL 20s`. A record answered for both
+// families (e.g. a CNAME) is one hop, with its lowest TTL
+func (r *Resolver) logTTLs(hostname, name string, answers []dns.RR, effective time.Duration) {
+	chain := cnameChain(name, answers)
+	if len(chain) == 0 {
+		r.logInfo("TTL for %s: %ds\n", hostname, int64(effective.Seconds()))
+		return
+	}
+
+	// the lowest TTL of each name's CNAME, and of the final name's records by type
+	cnameTTLs := map[string]uint32{}
+	final := strings.ToLower(chain[len(chain)-1])
+	finalTTLs := map[uint16]uint32{}
+	var finalTypes []uint16
+	for _, rr := range answers {
+		hdr := rr.Header()
+		owner := strings.ToLower(hdr.Name)
+		switch {
+		case hdr.Rrtype == dns.TypeCNAME:
+			if ttl, ok := cnameTTLs[owner]; !ok || hdr.Ttl < ttl {
+				cnameTTLs[owner] = hdr.Ttl
+			}
+		case owner == final:
+			ttl, ok := finalTTLs[hdr.Rrtype]
+			if !ok {
+				finalTypes = append(finalTypes, hdr.Rrtype)
+			}
+			if !ok || hdr.Ttl < ttl {
+				finalTTLs[hdr.Rrtype] = hdr.Ttl
+			}
+		}
+	}
+
+	hops := make([]string, 0, len(chain)+1)
+	owner := dns.Fqdn(name)
+	for _, target := range chain {
+		hops = append(hops, fmt.Sprintf("%s CNAME %s %ds", r.displayName(owner), r.displayName(target), cnameTTLs[strings.ToLower(owner)]))
+		owner = target
+	}
+	if len(finalTypes) > 0 {
+		records := make([]string, len(finalTypes))
+		for i, rrtype := range finalTypes {
+			records[i] = fmt.Sprintf("%s %ds", dns.TypeToString[rrtype], finalTTLs[rrtype])
+		}
+		hops = append(hops, fmt.Sprintf("%s %s", r.displayName(owner), strings.Join(records, ", ")))
+	}
+	r.logInfo("TTLs for %s: %s; effective TTL %ds\n", hostname, strings.Join(hops, " -> "), int64(effective.Seconds()))
+}
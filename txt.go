@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// the `-class` names of the query classes
+var queryClasses = map[string]uint16{"in": dns.ClassINET, "ch": dns.ClassCHAOS, "hs": dns.ClassHESIOD}
+
+// Look up the TXT records for `hostname` via the lower-level client, in the
+// configured class; `-class ch` with `version.bind` (or `hostname.bind`,
+// `id.server`) is the classic way to identify a server's software
+func (r *Resolver) ResolveTXT(ctx context.Context, hostname string) *ResolveResult {
+	startTime := time.Now()
+	result := &ResolveResult{Hostname: hostname}
+
+	if err := validateHostname(hostname, false); err != nil {
+		r.logError("Not resolving: %s Error - '%s'\n", hostname, err.Error())
+		result.Err = newResolveError(hostname, err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	class := r.class
+	if class == 0 {
+		class = dns.ClassINET
+	}
+	className := dns.ClassToString[class]
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(hostname), dns.TypeTXT)
+	msg.Question[0].Qclass = class
+	var resp *dns.Msg
+	queryCtx, answered := withServerRecorder(ctx)
+	ns, err := r.withFailover(queryCtx, func(ns nameServer) error {
+		var err error
+		resp, err = r.exchange(queryCtx, ns, msg)
+		return err
+	})
+	result.Server = answered.serverOr(ns)
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		var rcodeErr *RcodeError
+		if class != dns.ClassINET && errors.As(err, &rcodeErr) &&
+			(rcodeErr.Rcode == dns.RcodeRefused || rcodeErr.Rcode == dns.RcodeNotImplemented) {
+			// plenty of servers (and most public resolvers) don't answer outside IN
+			r.logError("%s declined the %s query for %s (%s): the server doesn't answer %s queries\n", result.Server, className, hostname, dns.RcodeToString[rcodeErr.Rcode], className)
+		} else {
+			r.logError("Failed to look up %s TXT records for %s via %s: Error - '%s'\n", className, hostname, result.Server, err.Error())
+		}
+		result.Err = newResolveError(hostname, err)
+		return result
+	}
+
+	for _, rr := range resp.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			result.Records = append(result.Records, fmt.Sprintf("%q", strings.Join(txt.Txt, "")))
+		}
+	}
+	if len(result.Records) == 0 {
+		r.logInfo("%s TXT records for %s via %s: none\n", className, r.displayName(hostname), result.Server)
+		return result
+	}
+	r.logInfo("%s TXT records for %s via %s: %s\n", className, r.displayName(hostname), result.Server, strings.Join(result.Records, ", "))
+	return result
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "syscall"
+
+// setting the receive buffer is only supported on Unix; elsewhere the
+// system's default is kept
+func udpRcvBufControl(size int) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
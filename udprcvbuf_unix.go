@@ -0,0 +1,26 @@
+//go:build unix
+
+package main
+
+import (
+	"strings"
+	"syscall"
+)
+
+// a dialer Control func setting the UDP sockets' receive buffer to `size`
+// bytes (SO_RCVBUF), so responses aren't dropped under high concurrency; the
+// kernel may cap it (e.g. net.core.rmem_max on Linux)
+func udpRcvBufControl(size int) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		if !strings.HasPrefix(network, "udp") {
+			return nil
+		}
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, size)
+		}); err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
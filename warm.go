@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// last-warm timestamps keyed by hostname, persisted via `-warm-state`
+type warmState map[string]time.Time
+
+// a missing state file is treated as an empty state (first run)
+func loadWarmState(path string) (warmState, error) {
+	state := warmState{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// write the state to `path`, via a temporary file so an interrupted run
+// never leaves a truncated one
+func (s warmState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// split `hostnames` into those due for warming and those warmed within `window` of `now`
+func (s warmState) pending(hostnames []string, window time.Duration, now time.Time) (due []string, skipped []string) {
+	for _, hostname := range hostnames {
+		if last, ok := s[hostname]; ok && now.Sub(last) < window {
+			skipped = append(skipped, hostname)
+		} else {
+			due = append(due, hostname)
+		}
+	}
+	return due, skipped
+}
+
+// record the successfully resolved hostnames as warmed at `now`
+func (s warmState) update(results []*ResolveResult, now time.Time) {
+	for _, result := range results {
+		if result.Err == nil {
+			s[result.Hostname] = now
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWarmStateSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "warm.json")
+	warmed := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	if err := (warmState{"ok.test": warmed}).save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := loadWarmState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state["ok.test"].Equal(warmed) {
+		t.Errorf("got %v, want %v", state["ok.test"], warmed)
+	}
+	// written via a temporary file, renamed into place
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("got %d files in the directory, want only the state file", len(files))
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// each hostname's addresses (sorted) as of the last run, persisted via `-watch-state`
+type watchState map[string][]string
+
+// a missing state file is treated as an empty state (first run), so every hostname is NEW
+func loadWatchState(path string) (watchState, error) {
+	state := watchState{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s watchState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// how the hostnames' addresses compared with the stored state
+type watchCounts struct {
+	changed, unchanged, new, removed int
+}
+
+// Log whether each hostname's addresses are NEW, CHANGED or UNCHANGED since
+// the stored state, and the stored hostnames no longer resolved as REMOVED,
+// then update the state to match. A failed lookup keeps its stored addresses,
+// so an outage isn't mistaken for a change. These are per-hostname lines, so
+// aren't logged when `r` is quiet
+func (s watchState) compare(r *Resolver, results []*ResolveResult) watchCounts {
+	var counts watchCounts
+	seen := map[string]bool{}
+	for _, result := range results {
+		seen[result.Hostname] = true
+		if result.Err != nil {
+			r.logWarning("%s: not compared, as the lookup failed (%s)\n", result.Hostname, shortError(result.Err))
+			continue
+		}
+		addrs := sortedIPStrings(result.IPs)
+		previous, ok := s[result.Hostname]
+		switch {
+		case !ok:
+			counts.new++
+			r.logInfo("NEW %s: [%s]\n", result.Hostname, strings.Join(addrs, ", "))
+		case !slices.Equal(previous, addrs):
+			counts.changed++
+			r.logInfo("CHANGED %s: [%s] -> [%s]\n", result.Hostname, strings.Join(previous, ", "), strings.Join(addrs, ", "))
+		default:
+			counts.unchanged++
+			r.logInfo("UNCHANGED %s: [%s]\n", result.Hostname, strings.Join(addrs, ", "))
+		}
+		s[result.Hostname] = addrs
+	}
+
+	var removed []string
+	for hostname := range s {
+		if !seen[hostname] {
+			removed = append(removed, hostname)
+		}
+	}
+	sort.Strings(removed)
+	for _, hostname := range removed {
+		counts.removed++
+		r.logInfo("REMOVED %s: [%s]\n", hostname, strings.Join(s[hostname], ", "))
+		delete(s, hostname)
+	}
+	return counts
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWatchStateCompare(t *testing.T) {
+	results := []*ResolveResult{
+		{Hostname: "same.test", IPs: []net.IP{net.ParseIP("192.0.2.1")}},
+		{Hostname: "changed.test", IPs: []net.IP{net.ParseIP("192.0.2.3")}},
+		{Hostname: "new.test", IPs: []net.IP{net.ParseIP("192.0.2.4")}},
+	}
+	for _, quiet := range []bool{false, true} {
+		state := watchState{
+			"same.test":    {"192.0.2.1"},
+			"changed.test": {"192.0.2.2"},
+			"gone.test":    {"192.0.2.5"},
+		}
+		r := &Resolver{quiet: quiet}
+		stdout, _ := captureLogs(t)
+		counts := state.compare(r, results)
+		if counts != (watchCounts{changed: 1, unchanged: 1, new: 1, removed: 1}) {
+			t.Errorf("got %+v", counts)
+		}
+		logged := stdout.String()
+		for _, line := range []string{"UNCHANGED same.test", "CHANGED changed.test: [192.0.2.2] -> [192.0.2.3]", "NEW new.test", "REMOVED gone.test"} {
+			if strings.Contains(logged, line) == quiet {
+				t.Errorf("quiet %t: got %q for %q", quiet, logged, line)
+			}
+		}
+		if _, ok := state["gone.test"]; ok || !slices.Equal(state["changed.test"], []string{"192.0.2.3"}) {
+			t.Errorf("state not updated: %v", state)
+		}
+	}
+}
+
+func TestWatchStateSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "watch.json")
+	state := watchState{"www.test": {"192.0.2.1"}}
+	for range 2 {
+		if err := state.save(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loaded, err := loadWatchState(path)
+	if err != nil || !slices.Equal(loaded["www.test"], []string{"192.0.2.1"}) {
+		t.Errorf("got %v, %v", loaded, err)
+	}
+	// no temporary files left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("got %d files, want 1", len(entries))
+	}
+}
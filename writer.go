@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Writes a run's output in one of the `-output` formats: `WriteResult` as each
+// hostname completes (called from concurrent goroutines, or in order once the
+// run completes with -stable), then `WriteSummary` once the run (or -interval
+// cycle) completes
+type OutputWriter interface {
+	WriteResult(result *ResolveResult)
+	WriteSummary(summary *Summary)
+}
+
+// a completed run (or -interval cycle), for `OutputWriter.WriteSummary`
+type Summary struct {
+	Label     string // e.g. the -interval cycle; empty for the run
+	Hostnames []string
+	Results   []*ResolveResult
+	Duration  time.Duration
+	Reason    string // why the run was cut short (see `cancelReason`); empty when it completed
+}
+
+// The default output: the resolver logs each lookup as it goes, so only the
+// compact lines (if enabled) and the summary line are written here
+type textWriter struct {
+	r         *Resolver
+	compact   bool
+	columns   []string // for the compact lines; nil for the default line
+	noSummary bool     // `-no-summary`
+}
+
+func (tw *textWriter) WriteResult(result *ResolveResult) {
+	if !tw.compact {
+		return
+	}
+	if tw.columns != nil {
+		LogInfo("%s\n", tw.r.compactColumns(result, tw.columns))
+	} else {
+		LogInfo("%s\n", tw.r.compactLine(result))
+	}
+}
+
+func (tw *textWriter) WriteSummary(summary *Summary) {
+	if tw.noSummary {
+		return
+	}
+	logSummary(summary.Label, summary.Hostnames, summary.Results, summary.Duration, summary.Reason)
+}
+
+// `-failures-only`: nothing as the hostnames complete, then only the failed
+// hostnames, so the summary is skipped
+type failuresWriter struct {
+	w      io.Writer
+	failed func(*ResolveResult) bool
+	json   bool
+}
+
+func (fw *failuresWriter) WriteResult(result *ResolveResult) {}
+
+func (fw *failuresWriter) WriteSummary(summary *Summary) {
+	if err := writeFailures(fw.w, summary.Results, fw.failed, fw.json); err != nil {
+		LogError("Failed to write failures: %s\n", err.Error())
+	}
+}
+
+// `-count-only`: stdout carries only the number of hostnames resolved, for
+// shell arithmetic; the failures are still logged to stderr
+type countWriter struct {
+	w io.Writer
+}
+
+func (cw *countWriter) WriteResult(result *ResolveResult) {}
+
+func (cw *countWriter) WriteSummary(summary *Summary) {
+	if _, err := fmt.Fprintln(cw.w, countResolved(summary.Results)); err != nil {
+		LogError("Failed to write the count: %s\n", err.Error())
+	}
+}
+
+// `-output hosts`: stdout carries only the hosts file, written once the run completes
+type hostsWriter struct {
+	w        io.Writer
+	allAddrs bool
+	header   bool
+}
+
+func (hw *hostsWriter) WriteResult(result *ResolveResult) {}
+
+func (hw *hostsWriter) WriteSummary(summary *Summary) {
+	if err := writeHosts(hw.w, summary.Results, hw.allAddrs, hw.header); err != nil {
+		LogError("Failed to write hosts: %s\n", err.Error())
+	}
+}
+
+// `-output dot`: stdout carries only the graph, written once the run completes
+type dotWriter struct {
+	w io.Writer
+}
+
+func (dw *dotWriter) WriteResult(result *ResolveResult) {}
+
+func (dw *dotWriter) WriteSummary(summary *Summary) {
+	if err := writeDot(dw.w, summary.Results); err != nil {
+		LogError("Failed to write the graph: %s\n", err.Error())
+	}
+}
+
+// stdout carries only the JSON lines, so there's no summary
+func (jw *jsonlWriter) WriteSummary(summary *Summary) {}
+
+// stdout carries only the CSV rows, so there's no summary
+func (cw *csvWriter) WriteSummary(summary *Summary) {}
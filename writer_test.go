@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// a resolved and a failed hostname
+func testResults() []*ResolveResult {
+	return []*ResolveResult{
+		{
+			Hostname: "ok.test",
+			IPs:      []net.IP{net.ParseIP("192.0.2.1").To4(), net.ParseIP("2001:db8::1")},
+			Reverse:  map[string][]string{"192.0.2.1": {"ok.test"}},
+			Server:   "192.0.2.53",
+			Duration: 12 * time.Millisecond,
+		},
+		{
+			Hostname: "nxdomain.test",
+			Err:      newResolveError("nxdomain.test", &net.DNSError{Err: "no such host", Name: "nxdomain.test", IsNotFound: true}),
+			Server:   "192.0.2.53",
+			Duration: 3 * time.Millisecond,
+		},
+	}
+}
+
+func TestOutputWriters(t *testing.T) {
+	r := &Resolver{servers: []nameServer{{addr: "192.0.2.53"}}}
+	tests := []struct {
+		name      string
+		newWriter func(w io.Writer) OutputWriter
+		logged    bool // written via the logger rather than `w`
+		want      string
+	}{
+		{
+			name:      "text",
+			newWriter: func(io.Writer) OutputWriter { return &textWriter{r: r} },
+			logged:    true,
+			want:      "INFO: Total duration for 2 addresses (ok.test, nxdomain.test): 15 ms; 1 of 2 resolved\n",
+		},
+		{
+			name:      "text, no summary",
+			newWriter: func(io.Writer) OutputWriter { return &textWriter{r: r, noSummary: true} },
+			logged:    true,
+			want:      "",
+		},
+		{
+			name:      "compact",
+			newWriter: func(io.Writer) OutputWriter { return &textWriter{r: r, compact: true} },
+			logged:    true,
+			want: "INFO: ok.test: [192.0.2.1, 2001:db8::1] (ok.test) 12ms\n" +
+				"INFO: nxdomain.test: FAILED (no such host) 3ms\n" +
+				"INFO: Total duration for 2 addresses (ok.test, nxdomain.test): 15 ms; 1 of 2 resolved\n",
+		},
+		{
+			name: "compact columns",
+			newWriter: func(io.Writer) OutputWriter {
+				return &textWriter{r: r, compact: true, columns: []string{"hostname", "ip"}, noSummary: true}
+			},
+			logged: true,
+			want:   "INFO: ok.test [192.0.2.1, 2001:db8::1]\nINFO: nxdomain.test\n",
+		},
+		{
+			name: "failures",
+			newWriter: func(w io.Writer) OutputWriter {
+				return &failuresWriter{w: w, failed: r.Failed}
+			},
+			want: "nxdomain.test\n",
+		},
+		{
+			name: "failures json",
+			newWriter: func(w io.Writer) OutputWriter {
+				return &failuresWriter{w: w, failed: r.Failed, json: true}
+			},
+			want: `[{"hostname":"nxdomain.test","error":"failed to resolve nxdomain.test: lookup nxdomain.test: no such host"}]` + "\n",
+		},
+		{
+			name:      "count",
+			newWriter: func(w io.Writer) OutputWriter { return &countWriter{w: w} },
+			want:      "1\n",
+		},
+		{
+			name:      "hosts",
+			newWriter: func(w io.Writer) OutputWriter { return &hostsWriter{w: w} },
+			want:      "192.0.2.1\tok.test\n",
+		},
+		{
+			name:      "hosts, all addresses",
+			newWriter: func(w io.Writer) OutputWriter { return &hostsWriter{w: w, allAddrs: true} },
+			want:      "192.0.2.1\tok.test\n2001:db8::1\tok.test\n",
+		},
+		{
+			name:      "dot",
+			newWriter: func(w io.Writer) OutputWriter { return &dotWriter{w: w} },
+			want: "digraph dns {\n\trankdir=LR;\n\tnode [shape=box];\n" +
+				"\t\"ok.test\";\n" +
+				"\t\"192.0.2.1\" [shape=ellipse];\n\t\"ok.test\" -> \"192.0.2.1\";\n" +
+				"\t\"2001:db8::1\" [shape=ellipse];\n\t\"ok.test\" -> \"2001:db8::1\";\n" +
+				"\t\"nxdomain.test\" [color=red, tooltip=\"no such host\"];\n" +
+				"}\n",
+		},
+		{
+			name:      "jsonl",
+			newWriter: func(w io.Writer) OutputWriter { return newJSONLWriter(w, r) },
+			want: `{"hostname":"ok.test","status":"resolved","ips":["192.0.2.1","2001:db8::1"],"reverse":{"192.0.2.1":["ok.test"]},"server":"192.0.2.53","duration_ms":12}` + "\n" +
+				`{"hostname":"nxdomain.test","status":"failed","error":"failed to resolve nxdomain.test: lookup nxdomain.test: no such host","server":"192.0.2.53","duration_ms":3}` + "\n",
+		},
+		{
+			name: "csv",
+			newWriter: func(w io.Writer) OutputWriter {
+				return newCSVWriter(w, r, []string{"hostname", "ip", "reverse", "duration", "error"})
+			},
+			want: "hostname,ip,reverse,duration,error\nok.test,192.0.2.1 2001:db8::1,ok.test,12,\nnxdomain.test,,,3,no such host\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := captureLogs(t)
+			var buf bytes.Buffer
+			out := tt.newWriter(&buf)
+			results := testResults()
+			for _, result := range results {
+				out.WriteResult(result)
+			}
+			out.WriteSummary(&Summary{Hostnames: []string{"ok.test", "nxdomain.test"}, Results: results, Duration: 15 * time.Millisecond})
+
+			got, other := buf.String(), stdout.String()
+			if tt.logged {
+				got, other = other, got
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if other != "" || stderr.Len() > 0 {
+				t.Errorf("unexpected output elsewhere: %q, %q", other, stderr.String())
+			}
+		})
+	}
+}
+
+// a pasted `host:port` is queried without its port, and written without it
+// where the output names hosts
+func TestHostPortOutput(t *testing.T) {
+	results := []*ResolveResult{
+		{Hostname: "web.test:443", QueryName: "web.test", IPs: []net.IP{net.ParseIP("192.0.2.5").To4()}},
+		{Hostname: "api.test.:8443", QueryName: "api.test.", IPs: []net.IP{net.ParseIP("192.0.2.6").To4()}},
+	}
+	var hosts bytes.Buffer
+	if err := writeHosts(&hosts, results, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := "192.0.2.5\tweb.test\n192.0.2.6\tapi.test\n"; hosts.String() != want {
+		t.Errorf("got hosts:\n%s\nwant:\n%s", hosts.String(), want)
+	}
+
+	var dot bytes.Buffer
+	if err := writeDot(&dot, results); err != nil {
+		t.Fatal(err)
+	}
+	want := "digraph dns {\n\trankdir=LR;\n\tnode [shape=box];\n" +
+		"\t\"web.test\";\n\t\"192.0.2.5\" [shape=ellipse];\n\t\"web.test\" -> \"192.0.2.5\";\n" +
+		"\t\"api.test\";\n\t\"192.0.2.6\" [shape=ellipse];\n\t\"api.test\" -> \"192.0.2.6\";\n" +
+		"}\n"
+	if dot.String() != want {
+		t.Errorf("got dot:\n%s\nwant:\n%s", dot.String(), want)
+	}
+}